@@ -0,0 +1,167 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+)
+
+var archiveExts = []string{".tar.gz", ".tgz", ".zip"}
+
+// archiveExt returns the archive extension of an asset name, or "" when the
+// asset is a bare binary.
+func archiveExt(name string) string {
+	lower := strings.ToLower(name)
+	for _, ext := range archiveExts {
+		if strings.HasSuffix(lower, ext) {
+			return name[len(name)-len(ext):]
+		}
+	}
+	return ""
+}
+
+type archiveEntry struct {
+	Name string
+	Mode fs.FileMode
+}
+
+// pickEntry chooses the file to install from an archive: the one the repo
+// list's hint names, by its path in the archive or its file name, or else
+// the one named like the binary, or else the first executable, ignoring
+// READMEs and licenses.
+func pickEntry(entries []archiveEntry, binary, hint string) (string, error) {
+	if hint != "" {
+		hint = strings.TrimPrefix(hint, "./")
+		for _, entry := range entries {
+			name := strings.TrimPrefix(entry.Name, "./")
+			if name == hint || strings.HasSuffix(name, "/"+hint) || name == hint+".exe" || strings.HasSuffix(name, "/"+hint+".exe") {
+				return entry.Name, nil
+			}
+		}
+		return "", fmt.Errorf("no file named %s in the archive", hint)
+	}
+	for _, entry := range entries {
+		base := path.Base(entry.Name)
+		if base == binary || base == binary+".exe" {
+			return entry.Name, nil
+		}
+	}
+	for _, entry := range entries {
+		if entry.Mode&0111 != 0 || strings.HasSuffix(strings.ToLower(entry.Name), ".exe") {
+			return entry.Name, nil
+		}
+	}
+	return "", fmt.Errorf("no executable found in the archive")
+}
+
+func extractBinary(archivePath, ext, binary, hint, target string) error {
+	if strings.EqualFold(ext, ".zip") {
+		return extractZip(archivePath, binary, hint, target)
+	}
+	return extractTarGz(archivePath, binary, hint, target)
+}
+
+func extractZip(archivePath, binary, hint, target string) error {
+	r, err := zip.OpenReader(archivePath)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	var entries []archiveEntry
+	for _, f := range r.File {
+		if f.Mode().IsRegular() {
+			entries = append(entries, archiveEntry{Name: f.Name, Mode: f.Mode()})
+		}
+	}
+	name, err := pickEntry(entries, binary, hint)
+	if err != nil {
+		return err
+	}
+
+	for _, f := range r.File {
+		if f.Name != name {
+			continue
+		}
+		src, err := f.Open()
+		if err != nil {
+			return err
+		}
+		defer src.Close()
+		return writeFile(src, target)
+	}
+	return nil
+}
+
+// extractTarGz reads the archive twice, once to pick the entry and once to
+// copy it out, since a tar stream can't be rewound.
+func extractTarGz(archivePath, binary, hint, target string) error {
+	var entries []archiveEntry
+	err := walkTarGz(archivePath, func(hdr *tar.Header, r io.Reader) (bool, error) {
+		if hdr.Typeflag == tar.TypeReg {
+			entries = append(entries, archiveEntry{Name: hdr.Name, Mode: hdr.FileInfo().Mode()})
+		}
+		return false, nil
+	})
+	if err != nil {
+		return err
+	}
+	name, err := pickEntry(entries, binary, hint)
+	if err != nil {
+		return err
+	}
+
+	return walkTarGz(archivePath, func(hdr *tar.Header, r io.Reader) (bool, error) {
+		if hdr.Name != name {
+			return false, nil
+		}
+		return true, writeFile(r, target)
+	})
+}
+
+func walkTarGz(archivePath string, visit func(hdr *tar.Header, r io.Reader) (bool, error)) error {
+	file, err := os.Open(archivePath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		return err
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		done, err := visit(hdr, tr)
+		if done || err != nil {
+			return err
+		}
+	}
+}
+
+func writeFile(r io.Reader, target string) error {
+	out, err := os.Create(target)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(out, r)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
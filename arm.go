@@ -0,0 +1,109 @@
+package main
+
+import (
+	"bufio"
+	"os"
+	"runtime"
+	"runtime/debug"
+	"strconv"
+	"strings"
+)
+
+// targetARM is the ARM version assets are picked for when targetArch is
+// arm, "6" or "7", or "" when it isn't known. Binaries built for ARMv6 run
+// on ARMv7, but not the other way around.
+var targetARM = detectARM()
+
+// detectARM reads the ARM version from the kernel, falling back to the GOARM
+// donut-utils itself was built with.
+func detectARM() string {
+	if runtime.GOARCH != "arm" {
+		return ""
+	}
+	if file, err := os.Open("/proc/cpuinfo"); err == nil {
+		defer file.Close()
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			key, value, ok := strings.Cut(scanner.Text(), ":")
+			if !ok || strings.TrimSpace(key) != "CPU architecture" {
+				continue
+			}
+			if version, err := strconv.Atoi(strings.TrimSpace(value)); err == nil {
+				if version >= 7 {
+					return "7"
+				}
+				return "6"
+			}
+		}
+	}
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range info.Settings {
+			if setting.Key == "GOARM" && (setting.Value == "6" || setting.Value == "7") {
+				return setting.Value
+			}
+		}
+	}
+	return ""
+}
+
+// parseARMArch splits an --arch like armv6 or armv7 into arm and its
+// version.
+func parseARMArch(arch string) (string, string) {
+	switch strings.ToLower(arch) {
+	case "armv6", "armv6l":
+		return "arm", "6"
+	case "armv7", "armv7l", "armhf":
+		return "arm", "7"
+	}
+	return arch, targetARM
+}
+
+// armRank orders ARM assets for an ARM version, highest first: the exact
+// version, then the other names that run on it, then plain arm. Assets that
+// won't run, like ARMv7 ones on an ARMv6 board, rank below zero.
+func armRank(name, version string) int {
+	lower := strings.ToLower(name)
+	switch version {
+	case "7":
+		switch {
+		case strings.Contains(lower, "armv7"):
+			return 3
+		case strings.Contains(lower, "armhf"):
+			return 2
+		case strings.Contains(lower, "armv6"):
+			return 1
+		}
+	case "6":
+		switch {
+		case strings.Contains(lower, "armv6"):
+			return 2
+		case strings.Contains(lower, "armv7"), strings.Contains(lower, "armhf"):
+			return -1
+		}
+	}
+	return 0
+}
+
+// bestARMAsset keeps the assets with the highest armRank for version, then
+// picks the best of those as bestAsset would.
+func bestARMAsset(assets []releaseAsset, version string) (releaseAsset, bool) {
+	best := -1
+	var ranked []releaseAsset
+	for _, asset := range assets {
+		if assetScore(asset.Name) < 0 {
+			continue
+		}
+		rank := armRank(asset.Name, version)
+		console.debugf("  %s ranks %d for ARMv%s", asset.Name, rank, version)
+		switch {
+		case rank > best:
+			best, ranked = rank, []releaseAsset{asset}
+		case rank == best:
+			ranked = append(ranked, asset)
+		}
+	}
+	if best < 0 {
+		return releaseAsset{}, false
+	}
+	return bestAsset(ranked)
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"fmt"
+)
+
+// listAssets shows what a repo's release offers and what the matcher makes
+// of it, for working out why a tool didn't install.
+func listAssets(opts options) error {
+	entries := parseRepoLines(opts.ListAssets)
+	if len(entries) != 1 {
+		return exitErr(ExitUsage, fmt.Errorf("expected a single owner/repo, got: %s", opts.ListAssets))
+	}
+	entry := entries[0]
+
+	ctx := context.Background()
+	release, err := fetchRelease(ctx, opts.API, entry)
+	if err != nil {
+		return exitErr(ExitNetwork, fmt.Errorf("failed to get latest release: %w", err))
+	}
+	details, err := fetchRepoInfo(ctx, opts.API, entry.Repo)
+	if err == nil {
+		release.Description = details.Description
+		release.DefaultBranch = details.DefaultBranch
+	}
+	release.Metadata, err = fetchRepoMetadata(ctx, opts.API, entry.Repo, release.DefaultBranch)
+	if err != nil {
+		console.errorf("Failed to read %s: %v", RepoMetadataFile, err)
+	}
+	release.MetadataLoaded = true
+
+	fmt.Fprintf(output, "%s %s has %d asset(s):\n\n", entry.Repo, release.Version, len(release.Assets))
+	for _, asset := range release.Assets {
+		note := ""
+		if isChecksumAsset(asset.Name) {
+			note = " (checksums)"
+		}
+		fmt.Fprintf(output, "  %-50s %10s%s\n", asset.Name, formatBytes(asset.Size), note)
+	}
+	fmt.Fprintln(output)
+
+	app, missing := matchRelease(ctx, opts, entry, release, nil)
+	switch {
+	case app != nil:
+		note := ""
+		if app.Universal {
+			note = ", as no asset is built for it and this one is platform-agnostic"
+		}
+		fmt.Fprintf(output, "%s would be installed on %s/%s%s.\n", green(app.Name), targetOS, targetArch, note)
+	case missing == nil || missing.Refused != "":
+		fmt.Fprintln(output, "The release would be refused.")
+	case entry.ExactAsset != "":
+		fmt.Fprintf(output, "None of the assets is named %q.\n", entry.ExactAsset)
+	case entry.Filter != "" && len(filterAssets(release.Assets, func(name string) bool { return matchesAssetPattern(entry.Filter, name) })) == 0:
+		fmt.Fprintf(output, "None of the assets matches the filter %q.\n", entry.Filter)
+	case release.Metadata != nil && release.Metadata.Asset != "":
+		fmt.Fprintf(output, "None of the assets matches the pattern %q from the repo's %s.\n", release.Metadata.Asset, RepoMetadataFile)
+	default:
+		fmt.Fprintf(output, "None of the assets matches %s/%s: an asset needs one of %q and one of %q in its name, and checksum files are never picked.\n", targetOS, targetArch, platformAliases(osAliases, targetOS), platformAliases(archAliases, targetArch))
+	}
+	return nil
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+const CacheFile = "cache.json"
+
+// cacheEntry is a cached response. Link is kept along with the body so a
+// cached page still leads to the next one.
+type cacheEntry struct {
+	ETag      string    `json:"etag,omitempty"`
+	Link      string    `json:"link,omitempty"`
+	Body      []byte    `json:"body"`
+	FetchedAt time.Time `json:"fetched_at"`
+}
+
+// apiCache keeps GitHub API responses between runs, keyed by URL. Entries
+// with an ETag are revalidated with If-None-Match, which costs no rate limit
+// when nothing changed. Entries without one are reused until they're older
+// than ttl.
+type apiCache struct {
+	path    string
+	ttl     time.Duration
+	mu      sync.Mutex
+	entries map[string]cacheEntry
+	dirty   bool
+}
+
+// cache is nil when caching is off, which makes every method a no-op.
+var cache *apiCache
+
+func loadCache(path string, ttl time.Duration) *apiCache {
+	c := &apiCache{path: path, ttl: ttl, entries: make(map[string]cacheEntry)}
+	data, err := os.ReadFile(path)
+	if err == nil {
+		json.Unmarshal(data, &c.entries)
+	}
+	return c
+}
+
+func (c *apiCache) lookup(url string) (cacheEntry, bool) {
+	if c == nil {
+		return cacheEntry{}, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[url]
+	if !ok || time.Since(entry.FetchedAt) > c.ttl {
+		return cacheEntry{}, false
+	}
+	return entry, true
+}
+
+func (c *apiCache) store(url string, entry cacheEntry) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[url] = entry
+	c.dirty = true
+}
+
+func (c *apiCache) save() error {
+	if c == nil || !c.dirty {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for url, entry := range c.entries {
+		if time.Since(entry.FetchedAt) > c.ttl {
+			delete(c.entries, url)
+		}
+	}
+	data, err := json.Marshal(c.entries)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(c.path, data, 0644)
+}
+
+// cachedGet serves a GitHub API GET from the cache where it can, and
+// otherwise sends it, conditionally when there's an ETag to revalidate.
+func cachedGet(url string, send func(etag string) (*http.Response, error)) (*http.Response, error) {
+	entry, ok := cache.lookup(url)
+	if ok && entry.ETag == "" {
+		console.debugf("Using cached %s", url)
+		return cachedResponse(entry), nil
+	}
+
+	resp, err := send(entry.ETag)
+	if err != nil {
+		return nil, err
+	}
+	if ok && resp.StatusCode == http.StatusNotModified {
+		resp.Body.Close()
+		entry.FetchedAt = time.Now()
+		cache.store(url, entry)
+		console.debugf("%s has not changed, using the cached response", url)
+		return cachedResponse(entry), nil
+	}
+	if cache == nil || resp.StatusCode != http.StatusOK {
+		return resp, nil
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	cache.store(url, cacheEntry{ETag: resp.Header.Get("ETag"), Link: resp.Header.Get("Link"), Body: body, FetchedAt: time.Now()})
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	return resp, nil
+}
+
+func cachedResponse(entry cacheEntry) *http.Response {
+	header := make(http.Header)
+	if entry.Link != "" {
+		header.Set("Link", entry.Link)
+	}
+	return &http.Response{
+		Status:     "200 OK",
+		StatusCode: http.StatusOK,
+		Header:     header,
+		Body:       io.NopCloser(bytes.NewReader(entry.Body)),
+	}
+}
@@ -0,0 +1,233 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/crypto/blake2b"
+)
+
+const (
+	AlgoAuto    = "auto"
+	AlgoSHA256  = "sha256"
+	AlgoSHA512  = "sha512"
+	AlgoBlake2b = "blake2b"
+)
+
+var checksumAlgos = map[string]func() hash.Hash{
+	AlgoSHA256: sha256.New,
+	AlgoSHA512: sha512.New,
+	AlgoBlake2b: func() hash.Hash {
+		h, _ := blake2b.New512(nil)
+		return h
+	},
+}
+
+var errNoChecksum = errors.New("no checksum published or pinned")
+
+var checksumSuffixes = []string{".sha256", ".sha512", ".b2", ".blake2b", ".sha256sum", ".sha512sum"}
+
+// isChecksumAsset reports whether a release asset holds checksums rather than
+// something installable, either for a single asset or for the whole release.
+func isChecksumAsset(name string) bool {
+	if isPerAssetChecksum(name) {
+		return true
+	}
+	lower := strings.ToLower(name)
+	for _, marker := range []string{"checksums", "sha256sums", "sha512sums", "b2sums"} {
+		if strings.Contains(lower, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+func isPerAssetChecksum(name string) bool {
+	lower := strings.ToLower(name)
+	for _, suffix := range checksumSuffixes {
+		if strings.HasSuffix(lower, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// checksumAssetFor prefers a checksum file dedicated to the asset over a
+// release-wide checksums list.
+func checksumAssetFor(assetName string, names []string) string {
+	var shared string
+	for _, name := range names {
+		if !isChecksumAsset(name) {
+			continue
+		}
+		if strings.HasPrefix(name, assetName+".") && isPerAssetChecksum(name) {
+			return name
+		}
+		if shared == "" && !isPerAssetChecksum(name) {
+			shared = name
+		}
+	}
+	return shared
+}
+
+// detectAlgos guesses which algorithms could have produced digest, using the
+// checksum file name first and the digest length as a fallback.
+func detectAlgos(algo, checksumName, digest string) []string {
+	if algo != "" && algo != AlgoAuto {
+		return []string{algo}
+	}
+
+	// The suffix settles it, as in tool-web2-linux-amd64.sha256. Otherwise
+	// look for a word naming the algorithm, as in SHA256SUMS or b2sums.txt,
+	// so a "b2" inside the tool's own name doesn't count.
+	lower := strings.ToLower(checksumName)
+	switch {
+	case strings.HasSuffix(lower, ".sha256"):
+		return []string{AlgoSHA256}
+	case strings.HasSuffix(lower, ".sha512"):
+		return []string{AlgoSHA512}
+	case strings.HasSuffix(lower, ".b2") || strings.HasSuffix(lower, ".blake2b"):
+		return []string{AlgoBlake2b}
+	}
+	words := strings.FieldsFunc(lower, func(r rune) bool { return r == '-' || r == '_' || r == '.' })
+	for i := len(words) - 1; i >= 0; i-- {
+		switch word := words[i]; {
+		case strings.HasPrefix(word, "sha512"):
+			return []string{AlgoSHA512}
+		case strings.HasPrefix(word, "sha256"):
+			return []string{AlgoSHA256}
+		case word == "b2" || strings.HasPrefix(word, "b2sum") || strings.HasPrefix(word, "blake2"):
+			return []string{AlgoBlake2b}
+		}
+	}
+
+	switch len(digest) {
+	case 64:
+		return []string{AlgoSHA256}
+	case 128:
+		return []string{AlgoSHA512, AlgoBlake2b}
+	}
+	return []string{AlgoSHA256, AlgoSHA512, AlgoBlake2b}
+}
+
+// findDigest pulls the digest for assetName out of a checksum file, which is
+// either a single bare digest or "<digest>  <name>" lines as written by
+// sha256sum and friends.
+func findDigest(data []byte, assetName string) (string, bool) {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	var lines [][]string
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		lines = append(lines, fields)
+	}
+
+	for _, fields := range lines {
+		if len(fields) >= 2 && strings.TrimPrefix(fields[len(fields)-1], "*") == assetName {
+			return strings.ToLower(fields[0]), true
+		}
+	}
+	if len(lines) == 1 {
+		return strings.ToLower(lines[0][0]), true
+	}
+	return "", false
+}
+
+// loadPinnedChecksums reads checksums the user vouches for themselves, one
+// "<digest>  owner/repo@version" per line in the style of sha256sum. A missing
+// file just means nothing is pinned.
+func loadPinnedChecksums(path string) (map[string]string, error) {
+	pinned := make(map[string]string)
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return pinned, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for i, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 || !strings.Contains(fields[1], "@") {
+			return nil, fmt.Errorf("%s:%d: expected \"<digest>  owner/repo@version\"", path, i+1)
+		}
+		pinned[fields[1]] = strings.ToLower(fields[0])
+	}
+	return pinned, nil
+}
+
+func fileDigest(path, algo string) (string, error) {
+	newHash, ok := checksumAlgos[algo]
+	if !ok {
+		return "", fmt.Errorf("unsupported checksum algorithm %q", algo)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	h := newHash()
+	if _, err := io.Copy(h, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func verifyDigest(path, expected string, algos []string, log logger) (string, error) {
+	for _, algo := range algos {
+		actual, err := fileDigest(path, algo)
+		if err != nil {
+			return "", err
+		}
+		log.debugf("%s of %s is %s, expected %s", algo, filepath.Base(path), actual, expected)
+		if actual == expected {
+			return algo, nil
+		}
+	}
+	return "", fmt.Errorf("checksum mismatch, expected %s", expected)
+}
+
+// verifyChecksumAsset checks a download against the release's checksum file
+// and returns the algorithm that matched.
+func verifyChecksumAsset(ctx context.Context, policy retryPolicy, app appInfo, path, algo string, log logger) (string, error) {
+	resp, err := policy.get(ctx, app.ChecksumURL)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		return "", fmt.Errorf("checksum file returned response code %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+
+	expected, ok := findDigest(data, app.Name)
+	if !ok {
+		return "", fmt.Errorf("%s has no entry for %s", app.ChecksumName, app.Name)
+	}
+
+	log.debugf("%s lists %s for %s", app.ChecksumName, expected, app.Name)
+	return verifyDigest(path, expected, detectAlgos(algo, app.ChecksumName, expected), log)
+}
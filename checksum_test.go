@@ -0,0 +1,32 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDetectAlgos(t *testing.T) {
+	sha256 := "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+	tests := []struct {
+		name   string
+		digest string
+		want   []string
+	}{
+		{"tool-web2-linux-amd64.sha256", "", []string{AlgoSHA256}},
+		{"b2tool-linux-amd64.sha512", "", []string{AlgoSHA512}},
+		{"tool-linux-amd64.b2", "", []string{AlgoBlake2b}},
+		{"SHA256SUMS", "", []string{AlgoSHA256}},
+		{"sha512sums.txt", "", []string{AlgoSHA512}},
+		{"tool_1.0_b2sums.txt", "", []string{AlgoBlake2b}},
+		{"web2-checksums.txt", sha256, []string{AlgoSHA256}},
+		{"checksums.txt", sha256 + sha256, []string{AlgoSHA512, AlgoBlake2b}},
+	}
+	for _, tt := range tests {
+		if got := detectAlgos(AlgoAuto, tt.name, tt.digest); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("detectAlgos(%q) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+	if got := detectAlgos(AlgoSHA512, "SHA256SUMS", sha256); !reflect.DeepEqual(got, []string{AlgoSHA512}) {
+		t.Errorf("an explicit algorithm was overridden: %v", got)
+	}
+}
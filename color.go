@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+const (
+	ColorAuto   = "auto"
+	ColorAlways = "always"
+	ColorNever  = "never"
+)
+
+var colorEnabled bool
+
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// setupColor decides once per run whether status messages are colored.
+// NO_COLOR wins over auto, and output meant for machines is never colored.
+func setupColor(mode string, machineOutput bool) error {
+	switch mode {
+	case ColorAlways:
+		colorEnabled = !machineOutput
+	case ColorNever:
+		colorEnabled = false
+	case ColorAuto:
+		_, noColor := os.LookupEnv("NO_COLOR")
+		colorEnabled = !noColor && !machineOutput && isTerminal(os.Stdout)
+	default:
+		return fmt.Errorf("invalid --color %q, expected %s, %s or %s", mode, ColorAuto, ColorAlways, ColorNever)
+	}
+	return nil
+}
+
+func colorize(code, s string) string {
+	if !colorEnabled {
+		return s
+	}
+	return "\x1b[" + code + "m" + s + "\x1b[0m"
+}
+
+func green(s string) string {
+	return colorize("32", s)
+}
+
+func red(s string) string {
+	return colorize("31", s)
+}
+
+func yellow(s string) string {
+	return colorize("33", s)
+}
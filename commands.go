@@ -0,0 +1,237 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	CmdInstall   = "install"
+	CmdList      = "list"
+	CmdUpdate    = "update"
+	CmdOutdated  = "outdated"
+	CmdSync      = "sync"
+	CmdRemove    = "remove"
+	CmdUninstall = "uninstall"
+	CmdApplyPath = "apply-path"
+	CmdEnv       = "env"
+	CmdMigrate   = "migrate"
+	CmdFreeze    = "freeze"
+)
+
+var commands = map[string]bool{
+	CmdInstall: true, CmdList: true, CmdUpdate: true, CmdOutdated: true, CmdSync: true, CmdRemove: true, CmdUninstall: true, CmdApplyPath: true, CmdEnv: true, CmdMigrate: true, CmdFreeze: true,
+}
+
+const usageHelp = `Usage:
+  donut-utils [flags] [command] [flags] [app...]
+
+Commands:
+  install [app...]  install the apps of the repo list, or just the named ones (the default)
+  list              print a table of the apps available for your platform
+  update [app...]   install apps with a newer release than the installed one, without asking
+  outdated [app...] list the installed apps with a newer release, without installing anything
+  sync              install every app of the repo list and remove the ones no longer in it
+  remove app...     remove the named apps installed by donut-utils, --profile removes a
+                    profile's apps and --all removes everything
+  uninstall         the same as remove
+  apply-path        add the install directory to PATH after --grace-period
+  env               print the environment variable of each flag and its value
+  migrate           record the binaries an early release installed in installed.json and
+                    mark their PATH line, --dry-run shows what would change
+  freeze            write the installed apps, their versions and the checksums of their
+                    binaries to --lockfile, for install --from-lock
+
+Apps are named by owner/repo, the repo alone or the name they're installed as.
+
+Flags:
+`
+
+// parseCommand picks the subcommand out of the arguments left after the
+// flags, then parses the flags that follow it, so both "--yes install" and
+// "install --yes" work. The other arguments, between flags or after them,
+// name apps. Without a subcommand it's an install.
+func parseCommand(fs *flag.FlagSet) (string, []string, error) {
+	command := fs.Arg(0)
+	if !commands[command] {
+		if command != "" {
+			return "", nil, fmt.Errorf("unknown command %q, expected install, list, update, outdated, sync, remove, uninstall, apply-path, env, migrate or freeze", command)
+		}
+		return CmdInstall, nil, nil
+	}
+	var names []string
+	args := fs.Args()[1:]
+	for {
+		if err := fs.Parse(args); err != nil {
+			return "", nil, err
+		}
+		if fs.NArg() == 0 {
+			break
+		}
+		names = append(names, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+	switch command {
+	case CmdUninstall:
+		command = CmdRemove
+	case CmdList, CmdSync, CmdApplyPath, CmdEnv, CmdMigrate, CmdFreeze:
+		if len(names) > 0 {
+			return "", nil, fmt.Errorf("%s doesn't take app names", command)
+		}
+	}
+	return command, names, nil
+}
+
+// appNamed reports whether name refers to an app, by its owner/repo, the
+// repo alone or the name it's installed as. GitLab projects can be named
+// without the gitlab: prefix.
+func appNamed(name, repo, installedName string) bool {
+	name = strings.ToLower(name)
+	repo = strings.ToLower(repo)
+	_, short := splitRepo(repo)
+	return name == repo || name == strings.TrimPrefix(repo, GitLabPrefix) || name == short || name == strings.ToLower(installedName)
+}
+
+// namedEntries keeps the repo list entries the names refer to. Names can
+// also be installed names, which aren't known until the release is looked
+// up, so when any name matches no repo every entry is kept.
+func namedEntries(entries []repoEntry, names []string) []repoEntry {
+	var named []repoEntry
+	for _, name := range names {
+		found := false
+		for _, entry := range entries {
+			if appNamed(name, entry.Repo, "") {
+				named = append(named, entry)
+				found = true
+			}
+		}
+		if !found {
+			return entries
+		}
+	}
+	return named
+}
+
+// namedApps keeps the apps the names refer to, and returns the names that
+// matched none.
+func namedApps(apps []appInfo, names []string) ([]appInfo, []string) {
+	var named []appInfo
+	var unknown []string
+	for _, name := range names {
+		found := false
+		for _, app := range apps {
+			if appNamed(name, app.Repo, app.binaryName()) {
+				if !containsApp(named, app) {
+					named = append(named, app)
+				}
+				found = true
+			}
+		}
+		if !found {
+			unknown = append(unknown, name)
+		}
+	}
+	return named, unknown
+}
+
+// remove deletes the named apps, or those installed with --profile when no
+// app is named, from the install directory and the manifest. Apps
+// donut-utils didn't install are left alone.
+func remove(opts options) error {
+	dir, err := installDir(opts)
+	if err != nil {
+		return fmt.Errorf("failed to resolve install directory: %w", err)
+	}
+	installed, err := loadManifest(dir)
+	if err != nil {
+		return fmt.Errorf("failed to read install manifest: %w", err)
+	}
+
+	var unknown []string
+	removed, failed := 0, 0
+	if len(opts.Names) == 0 {
+		removed, failed = installed.removeInstalled(func(path string, app installedApp) bool {
+			return app.Profile == opts.Profile
+		})
+		if removed == 0 && failed == 0 {
+			console.infof("Nothing in %s was installed with --profile %s", dir, opts.Profile)
+		}
+	}
+	for _, name := range opts.Names {
+		r, f := installed.removeInstalled(func(path string, app installedApp) bool {
+			return appNamed(name, app.Repo, filepath.Base(path))
+		})
+		if r == 0 && f == 0 {
+			unknown = append(unknown, name)
+		}
+		removed, failed = removed+r, failed+f
+	}
+
+	if removed > 0 {
+		if err := installed.save(dir); err != nil {
+			return fmt.Errorf("failed to save install manifest: %w", err)
+		}
+	}
+	if len(unknown) > 0 {
+		return fmt.Errorf("nothing installed by donut-utils in %s is named %s", dir, strings.Join(unknown, ", "))
+	}
+	if failed > 0 {
+		return exitErr(ExitPartial, fmt.Errorf("%d file(s) could not be removed", failed))
+	}
+	return nil
+}
+
+// removeInstalled deletes the installed files that match and drops them from
+// the manifest, counting the files removed and the ones that couldn't be.
+// The caller saves the manifest.
+func (m manifest) removeInstalled(match func(path string, app installedApp) bool) (removed, failed int) {
+	var paths []string
+	for path := range m {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		if !match(path, m[path]) {
+			continue
+		}
+		err := os.Remove(path)
+		if err != nil && !os.IsNotExist(err) {
+			console.errorf("%s", red(fmt.Sprint("Failed to remove ", path, ": ", err)))
+			failed++
+			continue
+		}
+		delete(m, path)
+		removed++
+		console.infof("Removed %s", path)
+	}
+	return removed, failed
+}
+
+// pruneProfile removes the apps installed with profile whose repo is no
+// longer in its repo list, so sync leaves the install directory matching
+// the list.
+func pruneProfile(dir, profile string, entries []repoEntry) {
+	listed := make(map[string]bool)
+	for _, entry := range entries {
+		listed[entry.Repo] = true
+	}
+	installed, err := loadManifest(dir)
+	if err != nil {
+		console.errorf("Failed to read install manifest: %v", err)
+		return
+	}
+	removed, _ := installed.removeInstalled(func(path string, app installedApp) bool {
+		return app.Profile == profile && app.Repo != "" && !listed[app.Repo]
+	})
+	if removed == 0 {
+		return
+	}
+	if err := installed.save(dir); err != nil {
+		console.errorf("Failed to save install manifest: %v", err)
+	}
+}
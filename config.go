@@ -0,0 +1,116 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	ConfigFile   = "config.json"
+	SourceConfig = "config"
+)
+
+// ConfigToken is the one config key that isn't a flag, so the GitHub token
+// can live in the config file without ever being typed on a command line.
+const ConfigToken = "token"
+
+var configFiles = []string{"config.yaml", "config.yml", ConfigFile}
+
+// defaultConfigPath is the first config file found in the user's config
+// directory, $XDG_CONFIG_HOME/donut-utils on Linux, or config.json there.
+func defaultConfigPath() string {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	dir := filepath.Join(configDir, "donut-utils")
+	for _, name := range configFiles {
+		if _, err := os.Stat(filepath.Join(dir, name)); err == nil {
+			return filepath.Join(dir, name)
+		}
+	}
+	return filepath.Join(dir, ConfigFile)
+}
+
+// configToken is the GitHub token from the config file.
+var configToken string
+
+// applyConfig fills in the flags still at their defaults from the config
+// file, a JSON or YAML object keyed by flag name, so both flags and the
+// environment win over it. A missing file is fine.
+func applyConfig(fs *flag.FlagSet, path string, sources map[string]string) error {
+	if path == "" {
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var config map[string]any
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &config)
+	default:
+		err = json.Unmarshal(data, &config)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+
+	for name, value := range config {
+		if name == ConfigToken {
+			if configToken, err = configString(value); err != nil {
+				return fmt.Errorf("%s: invalid %q: %w", path, name, err)
+			}
+			continue
+		}
+		if fs.Lookup(name) == nil {
+			return fmt.Errorf("%s: unknown option %q", path, name)
+		}
+		if sources[name] != SourceDefault {
+			continue
+		}
+
+		// Repeatable flags take a list, every item is set in turn.
+		values, ok := value.([]any)
+		if !ok {
+			values = []any{value}
+		}
+		for _, v := range values {
+			s, err := configString(v)
+			if err == nil {
+				err = fs.Set(name, s)
+			}
+			if err != nil {
+				return fmt.Errorf("%s: invalid %q: %w", path, name, err)
+			}
+		}
+		sources[name] = SourceConfig
+	}
+	return nil
+}
+
+func configString(value any) (string, error) {
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case int:
+		return strconv.Itoa(v), nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	}
+	return "", fmt.Errorf("expected a string, number or boolean, got %v", value)
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// closeCounter wraps a Fetcher and counts the response bodies still open.
+type closeCounter struct {
+	Fetcher
+	mu   sync.Mutex
+	open int
+}
+
+type countedBody struct {
+	io.ReadCloser
+	counter *closeCounter
+	once    sync.Once
+}
+
+func (b *countedBody) Close() error {
+	b.once.Do(func() {
+		b.counter.mu.Lock()
+		b.counter.open--
+		b.counter.mu.Unlock()
+	})
+	return b.ReadCloser.Close()
+}
+
+func (c *closeCounter) Do(req *http.Request) (*http.Response, error) {
+	resp, err := c.Fetcher.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	c.open++
+	c.mu.Unlock()
+	resp.Body = &countedBody{ReadCloser: resp.Body, counter: c}
+	return resp, nil
+}
+
+func TestDiscoverAppsClosesBodies(t *testing.T) {
+	testPlatform(t, "linux", "amd64")
+	policy := testAPI(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		repo := strings.TrimPrefix(r.URL.Path, "/repos/")
+		switch {
+		case strings.HasSuffix(repo, "/releases/latest"):
+			name := strings.TrimSuffix(strings.TrimPrefix(repo, "o/"), "/releases/latest")
+			fmt.Fprintf(w, `{"tag_name":"v1","author":{"login":"alice"},"assets":[{"name":"%s-linux-amd64"}]}`, name)
+		case strings.HasPrefix(repo, "o/missing"):
+			http.NotFound(w, r)
+		default:
+			fmt.Fprint(w, `{"description":"d","default_branch":""}`)
+		}
+	}))
+	counter := &closeCounter{Fetcher: policy.Fetcher}
+	policy.Fetcher = counter
+
+	var entries []repoEntry
+	for i := 0; i < 50; i++ {
+		entries = append(entries, repoEntry{Repo: fmt.Sprintf("o/tool%d", i)})
+	}
+	entries = append(entries, repoEntry{Repo: "o/missing"})
+
+	apps, _ := discoverApps(context.Background(), options{API: policy}, entries, nil)
+	if len(apps) != 50 {
+		t.Errorf("got %d apps, want 50", len(apps))
+	}
+	if counter.open != 0 {
+		t.Errorf("%d response bodies were left open", counter.open)
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/tabwriter"
+)
+
+// printAppTable lists the available apps for --list, one row each.
+func printAppTable(apps []appInfo) {
+	w := tabwriter.NewWriter(output, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tVERSION\tSIZE\tDESCRIPTION")
+	for _, app := range apps {
+		size := "-"
+		if app.Size > 0 {
+			size = formatBytes(app.Size)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", app.displayName(), app.Version, size, app.Description)
+	}
+	w.Flush()
+}
+
+type appJSON struct {
+	Repo        string `json:"repo,omitempty"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Version     string `json:"version"`
+	DownloadURL string `json:"download_url"`
+	Size        int64  `json:"size"`
+	InstallPath string `json:"install_path"`
+}
+
+// printAppJSON writes the available apps for --json as one JSON array.
+func printAppJSON(w io.Writer, dir string, apps []appInfo) error {
+	list := []appJSON{}
+	for _, app := range apps {
+		source := app.DownloadURL
+		if app.LocalPath != "" {
+			source = app.LocalPath
+		}
+		list = append(list, appJSON{
+			Repo:        app.Repo,
+			Name:        app.displayName(),
+			Description: app.Description,
+			Version:     app.Version,
+			DownloadURL: source,
+			Size:        app.Size,
+			InstallPath: app.installPath(dir),
+		})
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(list)
+}
+
+func printDryRun(dir string, apps []appInfo) {
+	fmt.Fprintln(output, "\n\nDry run, nothing will be downloaded. These apps would be installed:")
+	for _, app := range apps {
+		source := app.DownloadURL
+		if app.LocalPath != "" {
+			source = app.LocalPath
+		}
+		header := app.displayName()
+		if app.Version != "" {
+			header += " " + app.Version
+		}
+		fmt.Fprintf(output, "\n%s\n  from: %s\n  to:   %s\n", header, source, app.installPath(dir))
+	}
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+const (
+	EnvPrefix = "DONUT_UTILS_"
+	EnvToken  = EnvPrefix + "TOKEN"
+)
+
+const (
+	SourceDefault = "default"
+	SourceEnv     = "env"
+	SourceFlag    = "flag"
+)
+
+// envAliases are environment variables also read for a flag, after its own.
+var envAliases = map[string]string{
+	"api-base": "DONUT_GITHUB_API",
+}
+
+// shorthands are one letter flags standing in for a longer one. They have no
+// environment variable of their own.
+var shorthands = map[string]string{
+	"y": "yes",
+}
+
+func envName(flagName string) string {
+	return EnvPrefix + strings.ToUpper(strings.ReplaceAll(flagName, "-", "_"))
+}
+
+// applyEnv fills in every flag that wasn't given on the command line from its
+// DONUT_UTILS_ environment variable, so flags win over the environment. It
+// returns where each flag's value came from.
+func applyEnv(fs *flag.FlagSet) (map[string]string, error) {
+	sources := make(map[string]string)
+	fs.Visit(func(f *flag.Flag) {
+		sources[f.Name] = SourceFlag
+		if long, ok := shorthands[f.Name]; ok {
+			sources[long] = SourceFlag
+		}
+	})
+
+	var err error
+	fs.VisitAll(func(f *flag.Flag) {
+		if _, ok := shorthands[f.Name]; ok || err != nil || sources[f.Name] == SourceFlag {
+			return
+		}
+		sources[f.Name] = SourceDefault
+		name := envName(f.Name)
+		value, ok := os.LookupEnv(name)
+		if alias := envAliases[f.Name]; !ok && alias != "" {
+			name = alias
+			value, ok = os.LookupEnv(name)
+		}
+		if !ok {
+			return
+		}
+		if setErr := fs.Set(f.Name, value); setErr != nil {
+			err = fmt.Errorf("invalid %s: %w", name, setErr)
+			return
+		}
+		sources[f.Name] = SourceEnv
+	})
+	return sources, err
+}
+
+func printEnv(sources map[string]string) {
+	fmt.Fprint(output, "Each option can be set with an environment variable or in the config file. Flags take precedence over the environment, which takes precedence over the config file.\n\n")
+	flag.VisitAll(func(f *flag.Flag) {
+		if _, ok := shorthands[f.Name]; ok {
+			return
+		}
+		fmt.Fprintf(output, "%s=%s (%s, --%s)\n", envName(f.Name), f.Value.String(), sources[f.Name], f.Name)
+	})
+
+	token := "unset"
+	if _, ok := os.LookupEnv(EnvToken); ok {
+		token = "set"
+	}
+	fmt.Fprintf(output, "%s is %s, it is used before DONUT_GITHUB_TOKEN, GITHUB_TOKEN, GH_TOKEN, --token-file and the GitHub CLI login for GitHub requests\n", EnvToken, token)
+}
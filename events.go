@@ -0,0 +1,80 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	EventResolveStarted   = "resolve-started"
+	EventAssetMatched     = "asset-matched"
+	EventDownloadProgress = "download-progress"
+	EventInstallComplete  = "install-complete"
+	EventError            = "error"
+)
+
+type event struct {
+	Type  string    `json:"type"`
+	Time  time.Time `json:"time"`
+	Repo  string    `json:"repo,omitempty"`
+	App   string    `json:"app,omitempty"`
+	Bytes int64     `json:"bytes,omitempty"`
+	Total int64     `json:"total,omitempty"`
+	Path  string    `json:"path,omitempty"`
+	Error string    `json:"error,omitempty"`
+}
+
+// eventStream writes newline-delimited JSON events for wrappers that want to
+// render live progress. A nil *eventStream discards everything.
+type eventStream struct {
+	mu sync.Mutex
+	w  io.Writer
+	c  io.Closer
+}
+
+func openEventStream(target string) (*eventStream, error) {
+	switch target {
+	case "":
+		return nil, nil
+	case "stdout", "-":
+		return &eventStream{w: os.Stdout}, nil
+	case "stderr":
+		return &eventStream{w: os.Stderr}, nil
+	}
+
+	file, err := os.Create(target)
+	if err != nil {
+		return nil, err
+	}
+	return &eventStream{w: file, c: file}, nil
+}
+
+func (s *eventStream) emit(e event) {
+	if s == nil {
+		return
+	}
+	e.Time = time.Now().UTC()
+	line, err := json.Marshal(e)
+	if err != nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fmt.Fprintf(s.w, "%s\n", line)
+}
+
+func (s *eventStream) fail(repo, app string, err error) {
+	s.emit(event{Type: EventError, Repo: repo, App: app, Error: err.Error()})
+}
+
+func (s *eventStream) Close() error {
+	if s == nil || s.c == nil {
+		return nil
+	}
+	return s.c.Close()
+}
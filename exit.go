@@ -0,0 +1,43 @@
+package main
+
+// Exit statuses, also listed in the --help text.
+const (
+	ExitFailure  = 1
+	ExitUsage    = 2
+	ExitRepoList = 3
+	ExitNetwork  = 4
+	ExitPartial  = 5
+
+	// ExitInterrupted is what shells report for a program stopped by
+	// Ctrl-C, 128 plus SIGINT.
+	ExitInterrupted = 130
+)
+
+const exitCodesHelp = `
+Exit status:
+  0    success
+  1    unexpected failure
+  2    invalid flags
+  3    the repo list is missing or empty
+  4    looking up releases failed for every repo, or timed out
+  5    some apps failed to install
+  130  interrupted by Ctrl-C or SIGTERM
+`
+
+// exitError gives an error the exit status main should use for it.
+type exitError struct {
+	code int
+	err  error
+}
+
+func exitErr(code int, err error) error {
+	return &exitError{code: code, err: err}
+}
+
+func (e *exitError) Error() string {
+	return e.err.Error()
+}
+
+func (e *exitError) Unwrap() error {
+	return e.err
+}
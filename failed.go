@@ -0,0 +1,109 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+const FailedFile = "failed.json"
+
+func loadFailed(dir string) (map[string]bool, error) {
+	failed := make(map[string]bool)
+	data, err := os.ReadFile(filepath.Join(dir, FailedFile))
+	if os.IsNotExist(err) {
+		return failed, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var repos []string
+	err = json.Unmarshal(data, &repos)
+	if err != nil {
+		return nil, err
+	}
+	for _, repo := range repos {
+		failed[repo] = true
+	}
+	return failed, nil
+}
+
+// saveFailed records the repos that failed this run for --retry-failed. A run
+// with no failures clears the record.
+func saveFailed(dir string, failed map[string]bool) {
+	path := filepath.Join(dir, FailedFile)
+	if len(failed) == 0 {
+		os.Remove(path)
+		return
+	}
+
+	var repos []string
+	for repo := range failed {
+		repos = append(repos, repo)
+	}
+	sort.Strings(repos)
+
+	data, err := json.MarshalIndent(repos, "", "  ")
+	if err != nil {
+		console.errorf("Failed to record failed apps: %v", err)
+		return
+	}
+	err = os.WriteFile(path, data, 0644)
+	if err != nil {
+		console.errorf("Failed to record failed apps: %v", err)
+		return
+	}
+	console.infof("\n%d app(s) failed, run again with --retry-failed to retry just those.", len(repos))
+}
+
+func onlyFailed(entries []repoEntry, failed map[string]bool) []repoEntry {
+	var retry []repoEntry
+	for _, entry := range entries {
+		if failed[entry.Repo] {
+			retry = append(retry, entry)
+		}
+	}
+	return retry
+}
+
+// discoveryFailures finds the repos that produced neither an app nor an
+// unmatched report, which only happens when looking them up failed. Refused
+// releases come back as unmatched, so they're not retried.
+func discoveryFailures(entries []repoEntry, apps []appInfo, unmatched []unmatchedRepo) map[string]bool {
+	resolved := make(map[string]bool)
+	for _, app := range apps {
+		resolved[app.Repo] = true
+	}
+	for _, missing := range unmatched {
+		resolved[missing.Repo] = true
+	}
+
+	failed := make(map[string]bool)
+	for _, entry := range entries {
+		if !resolved[entry.Repo] {
+			failed[entry.Repo] = true
+		}
+	}
+	return failed
+}
+
+// discoveryErr is the exit status of a run that installed nothing wrong but
+// couldn't look up some repos, or refused their releases.
+func discoveryErr(failed map[string]bool, unmatched []unmatchedRepo) error {
+	refused := 0
+	for _, missing := range unmatched {
+		if missing.Refused != "" {
+			refused++
+		}
+	}
+	switch {
+	case len(failed) > 0:
+		return exitErr(ExitPartial, fmt.Errorf("looking up %d repo(s) failed", len(failed)))
+	case refused > 0:
+		return exitErr(ExitPartial, fmt.Errorf("%d repo(s) were refused", refused))
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+const (
+	GitLabPrefix     = "gitlab:"
+	DefaultGitLabAPI = "https://gitlab.com/api/v4"
+)
+
+// gitLabAPI is the root of the GitLab API, set by --gitlab-api to use a
+// self-hosted GitLab.
+var gitLabAPI = DefaultGitLabAPI
+
+// gitLabPattern matches repo list entries for GitLab projects, which can sit
+// in nested groups, like gitlab:group/subgroup/project.
+var gitLabPattern = regexp.MustCompile(`^gitlab:[A-Za-z0-9._-]+(/[A-Za-z0-9._-]+)+$`)
+
+// gitLabProject returns the project path of a gitlab: repo.
+func gitLabProject(repo string) (string, bool) {
+	if !strings.HasPrefix(repo, GitLabPrefix) {
+		return "", false
+	}
+	return strings.TrimPrefix(repo, GitLabPrefix), true
+}
+
+// splitRepo splits a repo into its owner and name. For GitLab projects the
+// owner is the group path.
+func splitRepo(repo string) (string, string) {
+	repo = strings.TrimPrefix(repo, GitLabPrefix)
+	if i := strings.LastIndex(repo, "/"); i >= 0 {
+		return repo[:i], repo[i+1:]
+	}
+	return "", repo
+}
+
+// projectAPI is the API URL of a GitLab project with path appended.
+func projectAPI(project, path string) string {
+	return gitLabAPI + "/projects/" + url.PathEscape(project) + path
+}
+
+// gitLabGet is get for GitLab API requests, answered from the cache when the
+// response hasn't changed.
+func (p retryPolicy) gitLabGet(ctx context.Context, url string) (*http.Response, error) {
+	return cachedGet(url, func(etag string) (*http.Response, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Accept", "application/json")
+		if etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+		return p.do(req)
+	})
+}
+
+func getGitLabJSON(ctx context.Context, policy retryPolicy, url string, v any) error {
+	resp, err := policy.gitLabGet(ctx, url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		return fmt.Errorf("received non-200 response code: %d", resp.StatusCode)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(body, v)
+}
+
+func fetchGitLabRepoInfo(ctx context.Context, policy retryPolicy, project string) (repoDetails, error) {
+	var info repoDetails
+	err := getGitLabJSON(ctx, policy, projectAPI(project, ""), &info)
+	return info, err
+}
+
+// fetchGitLabRelease looks up a GitLab project's release, the latest one
+// unless the entry pins a tag. Its assets are the release's links, the
+// source archives GitLab adds to every release are left out.
+func fetchGitLabRelease(ctx context.Context, policy retryPolicy, project string, entry repoEntry) (releaseInfo, error) {
+	releaseURL := projectAPI(project, "/releases/permalink/latest")
+	if entry.Version != "" {
+		releaseURL = projectAPI(project, "/releases/"+url.PathEscape(entry.Version))
+	}
+
+	var release struct {
+		TagName string `json:"tag_name"`
+		Author  struct {
+			Username string `json:"username"`
+		} `json:"author"`
+		Assets struct {
+			Links []struct {
+				Name           string `json:"name"`
+				URL            string `json:"url"`
+				DirectAssetURL string `json:"direct_asset_url"`
+			} `json:"links"`
+		} `json:"assets"`
+	}
+	if err := getGitLabJSON(ctx, policy, releaseURL, &release); err != nil {
+		return releaseInfo{}, err
+	}
+
+	var assets []releaseAsset
+	for _, link := range release.Assets.Links {
+		download := link.DirectAssetURL
+		if download == "" {
+			download = link.URL
+		}
+		assets = append(assets, releaseAsset{Name: link.Name, BrowserDownloadUrl: download})
+	}
+	return releaseInfo{
+		Author:  release.Author.Username,
+		Version: release.TagName,
+		Assets:  assets,
+	}, nil
+}
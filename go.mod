@@ -1,3 +1,14 @@
 module github.com/donuts-are-good/donut-utils
 
 go 1.20
+
+require (
+	github.com/ProtonMail/go-crypto v1.0.0
+	golang.org/x/crypto v0.17.0
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+require (
+	github.com/cloudflare/circl v1.3.3 // indirect
+	golang.org/x/sys v0.15.0 // indirect
+)
@@ -0,0 +1,264 @@
+package main
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const MaxRetryAfter = time.Minute
+
+// retryPolicy controls how hard a phase tries before giving up. API calls
+// want quick retries and short timeouts, downloads want room to be slow.
+type retryPolicy struct {
+	Retries int
+	Timeout time.Duration
+
+	// Fetcher sends the requests, a client using the shared transport and
+	// Timeout when it's nil.
+	Fetcher Fetcher
+}
+
+// Fetcher sends an HTTP request. *http.Client is one, and tests can use one
+// pointed at an httptest.Server so lookups and downloads never touch the
+// network.
+type Fetcher interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// transport is shared by every request so connections are reused. It bounds
+// connecting and waiting for response headers, which the per-phase
+// timeouts don't do when they're disabled for long downloads.
+var transport = newTransport(30*time.Second, nil)
+
+// newTransport sends requests through proxy, or the proxy set in the
+// environment when it's nil.
+func newTransport(timeout time.Duration, proxy *url.URL) *http.Transport {
+	proxyFunc := http.ProxyFromEnvironment
+	if proxy != nil {
+		proxyFunc = http.ProxyURL(proxy)
+	}
+	return &http.Transport{
+		Proxy:                 proxyFunc,
+		DialContext:           (&net.Dialer{Timeout: timeout, KeepAlive: 30 * time.Second}).DialContext,
+		TLSHandshakeTimeout:   timeout,
+		ResponseHeaderTimeout: timeout,
+		MaxIdleConns:          100,
+		IdleConnTimeout:       90 * time.Second,
+		ForceAttemptHTTP2:     true,
+	}
+}
+
+func (p retryPolicy) client() Fetcher {
+	if p.Fetcher != nil {
+		return p.Fetcher
+	}
+	return &http.Client{Timeout: p.Timeout, Transport: transport}
+}
+
+func (p retryPolicy) do(req *http.Request) (*http.Response, error) {
+	authorize(req)
+	client := p.client()
+	backoff := 500 * time.Millisecond
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req.Body = body
+		}
+		console.debugf("%s %s", req.Method, req.URL)
+		resp, err := client.Do(req)
+		wait := backoff
+		if err == nil && resp.StatusCode == http.StatusTooManyRequests {
+			after, ok := retryAfter(resp)
+			if !ok {
+				return resp, nil
+			}
+			wait = after
+		} else if err == nil && resp.StatusCode < 500 {
+			return resp, nil
+		}
+		if attempt >= p.Retries || req.Context().Err() != nil {
+			return resp, err
+		}
+
+		if err != nil {
+			console.infof("Request to %s failed, retrying in %s: %v", req.URL, wait, err)
+		} else {
+			resp.Body.Close()
+			console.infof("Request to %s returned %d, retrying in %s", req.URL, resp.StatusCode, wait)
+		}
+		select {
+		case <-time.After(wait):
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		}
+		backoff *= 2
+	}
+}
+
+// retryAfter reads how long a 429 response asks us to wait, in seconds or as
+// an HTTP date. Waits longer than MaxRetryAfter aren't worth retrying here.
+func retryAfter(resp *http.Response) (time.Duration, bool) {
+	header := resp.Header.Get("Retry-After")
+	if header == "" {
+		return 0, false
+	}
+
+	var wait time.Duration
+	if seconds, err := strconv.Atoi(header); err == nil {
+		wait = time.Duration(seconds) * time.Second
+	} else if at, err := http.ParseTime(header); err == nil {
+		wait = time.Until(at)
+	} else {
+		return 0, false
+	}
+
+	if wait < 0 {
+		wait = 0
+	}
+	return wait, wait <= MaxRetryAfter
+}
+
+func (p retryPolicy) get(ctx context.Context, url string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	return p.do(req)
+}
+
+// githubGet is get for GitHub API requests, authenticated when a token is
+// set so the 5000 requests an hour limit applies instead of 60, and answered
+// from the cache when the response hasn't changed.
+func (p retryPolicy) githubGet(ctx context.Context, url string) (*http.Response, error) {
+	return cachedGet(url, func(etag string) (*http.Response, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Accept", "application/vnd.github+json")
+		if etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+		return p.githubDo(req)
+	})
+}
+
+// githubDo sends a GitHub API request and turns an exhausted
+// rate limit into a *rateLimitError, so it isn't mistaken for a missing repo.
+func (p retryPolicy) githubDo(req *http.Request) (*http.Response, error) {
+	resp, err := p.do(req)
+	if err != nil {
+		return nil, err
+	}
+	if limited := checkRateLimit(resp); limited != nil {
+		resp.Body.Close()
+		return nil, limited
+	}
+	return resp, nil
+}
+
+// apiBase is the root of the GitHub API, set by --api-base to use a GitHub
+// Enterprise server.
+var apiBase = DefaultAPI
+
+// repoAPI is the API URL of a repo with path appended.
+func repoAPI(repo, path string) string {
+	return apiBase + "/repos/" + repo + path
+}
+
+// tokenEnv names an extra environment variable holding the GitHub token,
+// checked before the usual ones. It's set by --token-env. fileToken is the
+// token read from --token-file.
+var (
+	tokenEnv  string
+	fileToken string
+)
+
+var (
+	tokenOnce sync.Once
+	token     string
+)
+
+// githubToken finds the GitHub token once per run: in the environment, then
+// in --token-file, then in the config file, then from the GitHub CLI when
+// it's logged in.
+func githubToken() string {
+	tokenOnce.Do(func() {
+		names := []string{EnvToken, "DONUT_GITHUB_TOKEN", "GITHUB_TOKEN", "GH_TOKEN"}
+		if tokenEnv != "" {
+			names = append([]string{tokenEnv}, names...)
+		}
+		for _, name := range names {
+			if token = os.Getenv(name); token != "" {
+				return
+			}
+		}
+		if token = fileToken; token != "" {
+			return
+		}
+		if token = configToken; token != "" {
+			return
+		}
+		token = ghToken()
+	})
+	return token
+}
+
+// ghToken asks the GitHub CLI for the token it's logged in with, for the
+// host --api-base points at.
+func ghToken() string {
+	if _, err := exec.LookPath("gh"); err != nil {
+		return ""
+	}
+	host := "github.com"
+	if base, err := url.Parse(apiBase); err == nil && apiBase != DefaultAPI {
+		host = base.Hostname()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, "gh", "auth", "token", "--hostname", host).Output()
+	if err != nil {
+		return ""
+	}
+	console.debugf("Using the GitHub token gh is logged in with for %s", host)
+	return strings.TrimSpace(string(out))
+}
+
+// authorize adds the GitHub token to requests for the GitHub API and, when
+// that's the public API, to github.com, where release assets are downloaded
+// from. A GitHub Enterprise token from --api-base never goes to github.com.
+// Redirects to other hosts drop it.
+func authorize(req *http.Request) {
+	if req.Header.Get("Authorization") != "" {
+		return
+	}
+	host := req.URL.Host
+	base, err := url.Parse(apiBase)
+	onAPI := err == nil && host == base.Host
+	if !onAPI && (host != "github.com" || apiBase != DefaultAPI) {
+		return
+	}
+	if token := githubToken(); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+}
+
+func (p retryPolicy) head(ctx context.Context, url string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	return p.do(req)
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testAPI serves handler as the GitHub API for one test and returns a policy
+// reaching it through the Fetcher seam. The token lookup is used up first so
+// tests never ask gh for one.
+func testAPI(t *testing.T, handler http.Handler) retryPolicy {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	oldBase := apiBase
+	apiBase = server.URL
+	t.Cleanup(func() { apiBase = oldBase })
+	tokenOnce.Do(func() {})
+	return retryPolicy{Fetcher: server.Client()}
+}
+
+// testPlatform picks assets for goos/goarch during one test, with no ARM or
+// C library preference.
+func testPlatform(t *testing.T, goos, goarch string) {
+	t.Helper()
+	oldOS, oldArch, oldARM, oldLibc := targetOS, targetArch, targetARM, targetLibc
+	targetOS, targetArch, targetARM, targetLibc = goos, goarch, "", ""
+	t.Cleanup(func() { targetOS, targetArch, targetARM, targetLibc = oldOS, oldArch, oldARM, oldLibc })
+}
+
+// testCache turns the API cache on for one test.
+func testCache(t *testing.T) {
+	t.Helper()
+	old := cache
+	cache = &apiCache{ttl: time.Hour, entries: make(map[string]cacheEntry)}
+	t.Cleanup(func() { cache = old })
+}
+
+// withETag serves handler with an ETag on every response, answering 304
+// without a body or headers when the client already has it, like GitHub.
+// notModified counts those answers.
+func withETag(handler http.Handler, notModified *int) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, r)
+		etag := fmt.Sprintf(`"%x"`, sha256.Sum256(rec.Body.Bytes()))
+		if r.Header.Get("If-None-Match") == etag {
+			*notModified++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		for name, values := range rec.Header() {
+			w.Header()[name] = values
+		}
+		w.Header().Set("ETag", etag)
+		w.WriteHeader(rec.Code)
+		w.Write(rec.Body.Bytes())
+	})
+}
+
+func TestFetchRepoInfo(t *testing.T) {
+	policy := testAPI(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repos/o/tool":
+			fmt.Fprint(w, `{"description":"a tool","default_branch":"main"}`)
+		case "/repos/o/broken":
+			fmt.Fprint(w, `{"description":`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+
+	info, err := fetchRepoInfo(context.Background(), policy, "o/tool")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Description != "a tool" || info.DefaultBranch != "main" {
+		t.Errorf("got %+v", info)
+	}
+	if _, err := fetchRepoInfo(context.Background(), policy, "o/missing"); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("missing repo: got error %v, want a 404", err)
+	}
+	if _, err := fetchRepoInfo(context.Background(), policy, "o/broken"); err == nil {
+		t.Error("broken JSON: got no error")
+	}
+}
+
+func TestFetchRelease(t *testing.T) {
+	policy := testAPI(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repos/o/tool/releases/latest":
+			fmt.Fprint(w, `{"tag_name":"v2.0.0","author":{"login":"alice"},"assets":[{"name":"tool-linux-amd64","browser_download_url":"https://example.com/tool","size":10}]}`)
+		case "/repos/o/tool/releases/tags/v1.0.0":
+			fmt.Fprint(w, `{"tag_name":"v1.0.0","author":{"login":"alice"},"assets":[]}`)
+		case "/repos/o/broken/releases/latest":
+			fmt.Fprint(w, `not json`)
+		case "/repos/o/limited/releases/latest":
+			w.Header().Set("X-RateLimit-Remaining", "0")
+			w.Header().Set("X-RateLimit-Reset", "4102444800")
+			w.WriteHeader(http.StatusForbidden)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	ctx := context.Background()
+
+	release, err := fetchRelease(ctx, policy, repoEntry{Repo: "o/tool"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if release.Version != "v2.0.0" || release.Author != "alice" || len(release.Assets) != 1 || release.Assets[0].Size != 10 {
+		t.Errorf("latest release: got %+v", release)
+	}
+
+	release, err = fetchRelease(ctx, policy, repoEntry{Repo: "o/tool", Version: "v1.0.0"})
+	if err != nil || release.Version != "v1.0.0" {
+		t.Errorf("pinned release: got %+v, %v", release, err)
+	}
+
+	if _, err := fetchRelease(ctx, policy, repoEntry{Repo: "o/none"}); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("missing release: got error %v, want a 404", err)
+	}
+	if _, err := fetchRelease(ctx, policy, repoEntry{Repo: "o/broken"}); err == nil {
+		t.Error("broken JSON: got no error")
+	}
+	var limited *rateLimitError
+	if _, err := fetchRelease(ctx, policy, repoEntry{Repo: "o/limited"}); !errors.As(err, &limited) {
+		t.Errorf("exhausted rate limit: got error %v, want a *rateLimitError", err)
+	}
+}
+
+func TestMatchRelease(t *testing.T) {
+	testPlatform(t, "linux", "amd64")
+	release := releaseInfo{
+		Author:  "alice",
+		Version: "v1",
+		Assets: []releaseAsset{
+			{Name: "tool-darwin-arm64"},
+			{Name: "tool-linux-amd64", BrowserDownloadUrl: "https://example.com/tool-linux-amd64"},
+			{Name: "tool-linux-amd64.sha256", BrowserDownloadUrl: "https://example.com/tool-linux-amd64.sha256"},
+		},
+		MetadataLoaded: true,
+	}
+	ctx := context.Background()
+
+	app, missing := matchRelease(ctx, options{}, repoEntry{Repo: "o/tool"}, release, nil)
+	if app == nil || missing != nil {
+		t.Fatalf("got %v, %v, want an app", app, missing)
+	}
+	if app.Name != "tool-linux-amd64" || app.ChecksumName != "tool-linux-amd64.sha256" || app.Version != "v1" {
+		t.Errorf("got %+v", *app)
+	}
+
+	opts := options{TrustedAuthors: trustedAuthors{"o/tool": {"bob"}}}
+	if app, missing := matchRelease(ctx, opts, repoEntry{Repo: "o/tool"}, release, nil); app != nil || missing == nil || missing.Refused == "" {
+		t.Errorf("untrusted author: got %v, %v, want it refused", app, missing)
+	} else if failed := discoveryFailures([]repoEntry{{Repo: "o/tool"}}, nil, []unmatchedRepo{*missing}); len(failed) != 0 {
+		t.Errorf("the refused repo was recorded for --retry-failed: %v", failed)
+	}
+
+	app, missing = matchRelease(ctx, options{}, repoEntry{Repo: "o/tool", ExactAsset: "tool.zip"}, release, nil)
+	if app != nil || missing == nil || len(missing.Assets) != 3 {
+		t.Errorf("missing exact asset: got %v, %v, want it unmatched", app, missing)
+	}
+
+	app, missing = matchRelease(ctx, options{}, repoEntry{Repo: "o/tool", Filter: "*windows*"}, release, nil)
+	if app != nil || missing == nil {
+		t.Errorf("filter matching nothing: got %v, %v, want it unmatched", app, missing)
+	}
+
+	testPlatform(t, "freebsd", "amd64")
+	if app, missing := matchRelease(ctx, options{}, repoEntry{Repo: "o/tool"}, release, nil); app != nil || missing == nil {
+		t.Errorf("no asset for the platform: got %v, %v, want it unmatched", app, missing)
+	}
+}
+
+func TestAuthorize(t *testing.T) {
+	tokenOnce.Do(func() {})
+	oldToken, oldBase := token, apiBase
+	token = "secret"
+	t.Cleanup(func() { token, apiBase = oldToken, oldBase })
+
+	tests := []struct {
+		base string
+		url  string
+		want bool
+	}{
+		{DefaultAPI, "https://api.github.com/repos/o/r", true},
+		{DefaultAPI, "https://github.com/o/r/releases/download/v1/tool", true},
+		{DefaultAPI, "https://objects.githubusercontent.com/tool", false},
+		{"https://ghe.example.com/api/v3", "https://ghe.example.com/api/v3/repos/o/r", true},
+		{"https://ghe.example.com/api/v3", "https://github.com/o/r/releases/download/v1/tool", false},
+		{"https://ghe.example.com/api/v3", "https://api.github.com/repos/o/r", false},
+	}
+	for _, tt := range tests {
+		apiBase = tt.base
+		req := httptest.NewRequest(http.MethodGet, tt.url, nil)
+		authorize(req)
+		if got := req.Header.Get("Authorization") != ""; got != tt.want {
+			t.Errorf("with API %s, authorized %s: %v, want %v", tt.base, tt.url, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"sync/atomic"
+	"syscall"
+)
+
+// interrupted is set once Ctrl-C or SIGTERM stopped the downloads.
+var interrupted atomic.Bool
+
+// cancelOnInterrupt cancels the downloads on SIGINT or SIGTERM instead of
+// killing the process, so each one stops and removes its temp file before
+// returning. A second signal exits straight away. The returned func puts
+// the default handling back.
+func cancelOnInterrupt(cancel context.CancelFunc) func() {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-signals:
+		case <-done:
+			return
+		}
+		interrupted.Store(true)
+		console.errorf("\nAborting, cleaning up...")
+		cancel()
+		select {
+		case <-signals:
+			os.Exit(ExitInterrupted)
+		case <-done:
+		}
+	}()
+	return func() {
+		signal.Stop(signals)
+		close(done)
+	}
+}
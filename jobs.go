@@ -0,0 +1,53 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"sync"
+)
+
+// installAll runs downloadAndStore for every app on opts.Jobs workers,
+// numbered from 1 so stalls can be traced to one. Each app's output is
+// buffered and printed in one piece when it finishes. The result holds each
+// app's error, nil for the installed ones.
+func (in *installer) installAll(ctx context.Context, apps []appInfo) []error {
+	errs := make([]error, len(apps))
+	if in.wd.progress {
+		in.wd.board = newProgressBoard(apps)
+		outputMu.Lock()
+		liveBoard = in.wd.board
+		outputMu.Unlock()
+		defer in.wd.board.close()
+	}
+	workers := make(chan int, in.opts.Jobs)
+	for worker := 1; worker <= in.opts.Jobs; worker++ {
+		workers <- worker
+	}
+	var wg sync.WaitGroup
+	for i, app := range apps {
+		wg.Add(1)
+		go func(i int, app appInfo) {
+			defer wg.Done()
+			worker := <-workers
+			defer func() { workers <- worker }()
+			if ctx.Err() != nil {
+				errs[i] = ctx.Err()
+				return
+			}
+
+			var out bytes.Buffer
+			errs[i] = in.downloadAndStore(ctx, app, worker, &out)
+			if in.wd.board != nil {
+				in.wd.board.finish()
+			}
+
+			outputMu.Lock()
+			defer outputMu.Unlock()
+			liveBoard.hide()
+			out.WriteTo(output)
+			liveBoard.draw(true)
+		}(i, app)
+	}
+	wg.Wait()
+	return errs
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+const (
+	LibcAuto  = "auto"
+	LibcMusl  = "musl"
+	LibcGlibc = "glibc"
+)
+
+// targetLibc is the C library Linux assets are picked for, musl or glibc,
+// or "" when it isn't known or assets are staged for another machine.
+var targetLibc = detectLibc()
+
+// detectLibc looks for musl's dynamic loader, which Alpine and other musl
+// distributions install in place of glibc's.
+func detectLibc() string {
+	if runtime.GOOS != "linux" {
+		return ""
+	}
+	if loaders, _ := filepath.Glob("/lib/ld-musl-*.so.1"); len(loaders) > 0 {
+		return LibcMusl
+	}
+	if _, err := os.Stat("/etc/alpine-release"); err == nil {
+		return LibcMusl
+	}
+	return LibcGlibc
+}
+
+// libcRank orders Linux assets for a C library, highest first. On musl a
+// musl build beats a static one, which beats an unmarked one, and glibc
+// builds rank below zero since they won't run. On glibc the glibc builds
+// come first and everything else runs too.
+func libcRank(name, libc string) int {
+	lower := strings.ToLower(name)
+	glibc := strings.Contains(lower, "gnu") || strings.Contains(lower, "glibc")
+	switch libc {
+	case LibcMusl:
+		switch {
+		case strings.Contains(lower, "musl"):
+			return 2
+		case strings.Contains(lower, "static"):
+			return 1
+		case glibc:
+			return -1
+		}
+	case LibcGlibc:
+		if glibc {
+			return 1
+		}
+	}
+	return 0
+}
+
+// preferLibc keeps the installable assets with the highest libcRank, or
+// none when all of them need another C library.
+func preferLibc(assets []releaseAsset, libc string) []releaseAsset {
+	best := -1
+	var ranked []releaseAsset
+	for _, asset := range assets {
+		if assetScore(asset.Name) < 0 {
+			continue
+		}
+		rank := libcRank(asset.Name, libc)
+		console.debugf("  %s ranks %d for %s", asset.Name, rank, libc)
+		switch {
+		case rank > best:
+			best, ranked = rank, []releaseAsset{asset}
+		case rank == best:
+			ranked = append(ranked, asset)
+		}
+	}
+	if best < 0 {
+		return nil
+	}
+	return ranked
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// discoverLocal offers the platform binaries found in a local directory, such
+// as a freshly built dist/, so the install flow can be tested without GitHub.
+func discoverLocal(dir string) ([]appInfo, []unmatchedRepo) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		console.errorf("Failed to read local source directory: %v", err)
+		return nil, nil
+	}
+
+	var availableApps []appInfo
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() || !matchesPlatform(entry.Name(), targetOS, targetArch) {
+			continue
+		}
+		if (targetArch == "arm" && armRank(entry.Name(), targetARM) < 0) || (targetOS == "linux" && libcRank(entry.Name(), targetLibc) < 0) {
+			continue
+		}
+		var size int64
+		if info, err := entry.Info(); err == nil {
+			size = info.Size()
+		}
+		availableApps = append(availableApps, appInfo{
+			Name:        entry.Name(),
+			Size:        size,
+			Description: "Local build from " + dir,
+			LocalPath:   filepath.Join(dir, entry.Name()),
+		})
+	}
+	return availableApps, nil
+}
+
+func copyLocal(src, target string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(target)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	_, err = io.Copy(out, in)
+	return err
+}
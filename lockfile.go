@@ -0,0 +1,134 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const LockFile = "donut-utils.lock"
+
+// lockedApp is one app of a lockfile: the exact release asset it came from
+// and the digest of the binary installed from it, so install --from-lock
+// reproduces the same binary or refuses to.
+type lockedApp struct {
+	Repo    string `json:"repo"`
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	Asset   string `json:"asset"`
+	URL     string `json:"url,omitempty"`
+	SHA256  string `json:"sha256"`
+}
+
+// freeze writes the apps installed.json records to --lockfile, with the
+// digest of each binary computed again from disk, so a working set of tools
+// can be shared and installed elsewhere with install --from-lock. Apps
+// without a known repo and version, like ones installed from --local-source,
+// can't be installed again and are left out.
+func freeze(opts options) error {
+	dir, err := installDir(opts)
+	if err != nil {
+		return fmt.Errorf("failed to resolve install directory: %w", err)
+	}
+	installed, err := loadManifest(dir)
+	if err != nil {
+		return fmt.Errorf("failed to read install manifest: %w", err)
+	}
+	var paths []string
+	for path := range installed {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	var locked []lockedApp
+	failed := 0
+	for _, path := range paths {
+		app := installed[path]
+		if app.Repo == "" || app.Version == "" || !strings.HasPrefix(app.URL, "http") {
+			console.infof("%s", yellow(fmt.Sprintf("Leaving %s out of the lockfile, it wasn't installed from a known release", path)))
+			continue
+		}
+		digest, err := fileDigest(path, AlgoSHA256)
+		if err != nil {
+			console.errorf("%s", red(fmt.Sprintf("Failed to hash %s: %v", path, err)))
+			failed++
+			continue
+		}
+		if app.SHA256 != "" && digest != app.SHA256 {
+			console.errorf("%s", red(fmt.Sprintf("Leaving %s out of the lockfile, it changed since it was installed, reinstall it with --force first", path)))
+			failed++
+			continue
+		}
+		locked = append(locked, lockedApp{
+			Repo:    app.Repo,
+			Name:    filepath.Base(path),
+			Version: app.Version,
+			Asset:   app.Asset,
+			URL:     app.URL,
+			SHA256:  digest,
+		})
+	}
+	if len(locked) == 0 {
+		return fmt.Errorf("nothing installed in %s can be written to a lockfile", dir)
+	}
+
+	lockPath, err := expandPath(opts.LockFile)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(locked, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(lockPath, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write lockfile: %w", err)
+	}
+	console.infof("Wrote %d app(s) to %s", len(locked), displayPath(lockPath))
+	if failed > 0 {
+		return exitErr(ExitPartial, fmt.Errorf("%d app(s) could not be written to the lockfile", failed))
+	}
+	return nil
+}
+
+// loadLockFile reads a lockfile written by freeze as repo list entries that
+// pin each app to its release and exact asset and carry the digest its
+// binary must have.
+func loadLockFile(path string) ([]repoEntry, error) {
+	path, err := expandPath(path)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var locked []lockedApp
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&locked); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	entries := make([]repoEntry, 0, len(locked))
+	for i, app := range locked {
+		where := fmt.Sprintf("%s: entry %d", path, i+1)
+		if !repoPattern.MatchString(app.Repo) && !gitLabPattern.MatchString(app.Repo) {
+			return nil, fmt.Errorf("%s: invalid repo %q, expected owner/repo or gitlab:group/project", where, app.Repo)
+		}
+		if app.Version == "" || app.Asset == "" || app.SHA256 == "" {
+			return nil, fmt.Errorf("%s: %s needs a version, an asset and a sha256", where, app.Repo)
+		}
+		entries = append(entries, repoEntry{
+			Repo:         app.Repo,
+			Version:      app.Version,
+			Alias:        filepath.Base(app.Name),
+			ExactAsset:   app.Asset,
+			LockedSHA256: strings.ToLower(app.SHA256),
+		})
+	}
+	return entries, nil
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFreeze(t *testing.T) {
+	dir := t.TempDir()
+	m := make(manifest)
+	for _, app := range []installedApp{
+		{Repo: "owner/tool", Version: "v1.2.0", Asset: "tool-linux-amd64.tar.gz", URL: "https://example.com/tool-linux-amd64.tar.gz"},
+		{Repo: "owner/changed", Version: "v1", Asset: "changed-linux-amd64", URL: "https://example.com/changed-linux-amd64"},
+		{Asset: "local-linux-amd64", URL: "/srv/bin/local-linux-amd64"},
+	} {
+		name, _, _ := strings.Cut(app.Asset, "-")
+		app.Path = filepath.Join(dir, name)
+		if err := os.WriteFile(app.Path, []byte(name), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		digest, err := fileDigest(app.Path, AlgoSHA256)
+		if err != nil {
+			t.Fatal(err)
+		}
+		app.SHA256 = digest
+		m[app.Path] = app
+	}
+	if err := os.WriteFile(filepath.Join(dir, "changed"), []byte("patched"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.save(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	lock := filepath.Join(t.TempDir(), LockFile)
+	var exit *exitError
+	if err := freeze(options{InstallDir: dir, LockFile: lock}); !errors.As(err, &exit) || exit.code != ExitPartial {
+		t.Errorf("got %v, want exit status %d for the changed binary", err, ExitPartial)
+	}
+
+	entries, err := loadLockFile(lock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("got %d apps in the lockfile, want only owner/tool: %+v", len(entries), entries)
+	}
+	want := repoEntry{Repo: "owner/tool", Version: "v1.2.0", Alias: "tool", ExactAsset: "tool-linux-amd64.tar.gz", LockedSHA256: m[filepath.Join(dir, "tool")].SHA256}
+	if entries[0] != want {
+		t.Errorf("got %+v, want %+v", entries[0], want)
+	}
+}
+
+func TestLoadLockFileRejectsIncompleteEntries(t *testing.T) {
+	for name, data := range map[string]string{
+		"no sha256":   `[{"repo": "owner/tool", "name": "tool", "version": "v1", "asset": "tool"}]`,
+		"bad repo":    `[{"repo": "tool", "name": "tool", "version": "v1", "asset": "tool", "sha256": "ab"}]`,
+		"unknown key": `[{"repo": "owner/tool", "name": "tool", "version": "v1", "asset": "tool", "sha256": "ab", "sha": "ab"}]`,
+	} {
+		lock := filepath.Join(t.TempDir(), LockFile)
+		if err := os.WriteFile(lock, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := loadLockFile(lock); err == nil {
+			t.Errorf("%s: loaded without an error", name)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+const (
+	LevelQuiet = iota
+	LevelNormal
+	LevelVerbose
+)
+
+// verbosity is how much gets printed, set once per run by --quiet and
+// --verbose. Errors and the final summary are always printed.
+var verbosity = LevelNormal
+
+func setupVerbosity(quiet, verbose bool) error {
+	switch {
+	case quiet && verbose:
+		return fmt.Errorf("--quiet and --verbose can't be used together")
+	case quiet:
+		verbosity = LevelQuiet
+	case verbose:
+		verbosity = LevelVerbose
+	default:
+		verbosity = LevelNormal
+	}
+	return nil
+}
+
+// logger prints messages at a level, to out or to stdout when out is nil.
+// Messages get their own line.
+type logger struct {
+	out io.Writer
+}
+
+// console is the logger for messages that aren't grouped per app.
+var console logger
+
+// output is where everything meant for people goes: stdout, or stderr when
+// stdout carries JSON.
+var output = os.Stdout
+
+func (l logger) printf(level int, format string, a ...any) {
+	if verbosity < level {
+		return
+	}
+	if l.out != nil {
+		fmt.Fprintf(l.out, format+"\n", a...)
+		return
+	}
+	outputMu.Lock()
+	defer outputMu.Unlock()
+	liveBoard.hide()
+	fmt.Fprintf(output, format+"\n", a...)
+	liveBoard.draw(true)
+}
+
+func (l logger) errorf(format string, a ...any) {
+	l.printf(LevelQuiet, format, a...)
+}
+
+func (l logger) infof(format string, a ...any) {
+	l.printf(LevelNormal, format, a...)
+}
+
+// debugf is for detail that helps figure out why something happened, like
+// the requests made and how assets were picked.
+func (l logger) debugf(format string, a ...any) {
+	l.printf(LevelVerbose, format, a...)
+}
@@ -1,246 +1,1116 @@
 package main
 
 import (
-	"bufio"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
-	"net/http"
+	"net/url"
 	"os"
-	"os/user"
 	"path/filepath"
+	"regexp"
 	"runtime"
 	"strings"
-	"time"
 )
 
 const (
-	BaseURL     = "https://api.github.com/repos/"
+	DefaultAPI  = "https://api.github.com"
 	ReposList   = "repolist.txt"
 	DownloadDir = ".donut-utils"
 )
 
+type appInfo struct {
+	Repo        string
+	Version     string
+	Name        string
+	DisplayName string
+	Description string
+	DownloadURL string
+	AssetURL    string
+	BinaryName  string
+	Size        int64
+	LocalPath   string
+	InstallDir  string
+	Universal   bool
+
+	// Pinned is set when the repo list pins Version, so update installs
+	// exactly that release.
+	Pinned bool
+
+	// LockedSHA256 is the digest install --from-lock requires of the
+	// installed binary, which for an archive differs from the download's.
+	LockedSHA256 string
+
+	ChecksumName string
+	ChecksumURL  string
+
+	SignatureName string
+	SignatureURL  string
+	VerifyKey     string
+
+	// Extract names the file to install from an archive asset.
+	Extract string
+}
+
+func (app appInfo) displayName() string {
+	if app.DisplayName != "" {
+		return app.DisplayName
+	}
+	return app.Name
+}
+
+// versionSuffix matches a version like -v1.2.3, -v2 or _1.2 and everything
+// after it, which is usually the OS, arch and archive extension.
+var versionSuffix = regexp.MustCompile(`[-_](v\d+(\.\d+)*|\d+(\.\d+)+)([-_+.].*)?$`)
+
+var platformWords = map[string]bool{
+	"linux": true, "darwin": true, "macos": true, "osx": true, "windows": true, "win64": true, "win32": true, "freebsd": true, "openbsd": true, "netbsd": true,
+	"amd64": true, "x86_64": true, "x64": true, "386": true, "i386": true, "i686": true, "x86": true, "arm64": true, "aarch64": true, "armv8": true, "arm": true, "armv6": true, "armv7": true, "armhf": true, "universal": true,
+}
+
+// binaryName is the file name the app is installed as. Unless the repo list
+// or metadata picked one, it's the asset name without its version, or
+// without its OS and arch when there's no version. A .exe is kept.
+func (app appInfo) binaryName() string {
+	if app.BinaryName != "" {
+		return app.BinaryName
+	}
+
+	name, ext := strings.TrimSuffix(app.Name, archiveExt(app.Name)), ""
+	if strings.HasSuffix(strings.ToLower(name), ".exe") {
+		name, ext = name[:len(name)-4], name[len(name)-4:]
+	}
+
+	if stripped := versionSuffix.ReplaceAllString(name, ""); stripped != "" && stripped != name {
+		return stripped + ext
+	}
+
+	for i := 1; i < len(name); i++ {
+		if name[i] != '-' && name[i] != '_' {
+			continue
+		}
+		word, _, _ := strings.Cut(strings.ReplaceAll(name[i+1:], "_", "-"), "-")
+		if platformWords[strings.ToLower(word)] {
+			return name[:i] + ext
+		}
+	}
+	return name + ext
+}
+
+// installPath is where the app ends up, in its own install directory when
+// the repo list gave it one.
+func (app appInfo) installPath(dir string) string {
+	if app.InstallDir != "" {
+		dir = app.InstallDir
+	}
+	return filepath.Join(dir, app.binaryName())
+}
+
+// prefixName puts --prefix-name in front of the installed file name, with
+// {owner} and {repo} replaced by the app's repo. Apps already carrying the
+// prefix, like ones saved by --resolve-only, are left as they are.
+func (app appInfo) prefixName(prefix string) appInfo {
+	owner, repo := splitRepo(app.Repo)
+	prefix = strings.NewReplacer("{owner}", owner, "{repo}", repo).Replace(prefix)
+	if name := app.binaryName(); !strings.HasPrefix(name, prefix) {
+		app.BinaryName = prefix + name
+	}
+	return app
+}
+
+type releaseAsset struct {
+	Name               string `json:"name"`
+	BrowserDownloadUrl string `json:"browser_download_url"`
+	URL                string `json:"url"`
+	Size               int64  `json:"size"`
+}
+
+// releaseInfo is what discovery needs to know about a repo's release,
+// however it was looked up.
+type releaseInfo struct {
+	Description   string
+	DefaultBranch string
+	Author        string
+	Version       string
+	Assets        []releaseAsset
+
+	Metadata       *repoMetadata
+	MetadataLoaded bool
+}
+
 func main() {
-	fmt.Println(`     _                   _   
-  __| | ___  _ __  _   _| |_ 
- / _' |/ _ \| '_ \| | | | __|
-| (_| | (_) | | | | |_| | |_ 
- \__,_|_____|_| |_|\__,_|\__|
- _   _| |_(_| |___           
-| | | | __| | / __|          
-| |_| | |_| | \__ \          
- \__,_|\__|_|_|___/          
-                             `)
-	fmt.Println("donut-utils is a collection of cli utilities focusing on convenience and human readable output.\n\nThe applications will be downloaded from Github, and placed in ~/.donut-utils and then ~/.donut-utils will be added to your path.\n\nfor more information, visit the url below:\nhttps://github.com/donuts-are-good/donut-utils\n\nTo abort this process, press CTRL C now.")
-	time.Sleep(3 * time.Second)
-	data, err := os.ReadFile(ReposList)
-	if err != nil {
-		fmt.Println("Failed to read repos list file:", err)
+	err := run()
+	if err == nil {
 		return
 	}
+	if msg := err.Error(); len(msg) > 0 {
+		fmt.Fprintln(os.Stderr, red(strings.ToUpper(msg[:1])+msg[1:]))
+	}
 
-	usr, err := user.Current()
+	var exit *exitError
+	if errors.As(err, &exit) {
+		os.Exit(exit.code)
+	}
+	os.Exit(ExitFailure)
+}
+
+// run is the whole program. Its error decides the exit status, see exitError.
+func run() error {
+	opts, err := parseFlags()
 	if err != nil {
-		fmt.Println("Failed to get current user:", err)
-		return
+		return exitErr(ExitUsage, err)
 	}
 
-	downloadPath := filepath.Join(usr.HomeDir, DownloadDir)
-	err = os.MkdirAll(downloadPath, 0755)
+	switch opts.Command {
+	case CmdApplyPath:
+		return applyPath(opts)
+	case CmdMigrate:
+		return migrate(opts)
+	case CmdFreeze:
+		return freeze(opts)
+	case CmdEnv:
+		printEnv(opts.sources)
+		return nil
+	case CmdRemove:
+		if !opts.Uninstall {
+			return remove(opts)
+		}
+	}
+
+	if opts.PrintVersion {
+		printVersion()
+		return nil
+	}
+
+	if opts.SelfUpdate {
+		return selfUpdate(opts)
+	}
+
+	if opts.ListAssets != "" {
+		return listAssets(opts)
+	}
+
+	if opts.PrunePath {
+		return prunePath(opts)
+	}
+
+	if opts.Uninstall {
+		return uninstall(opts)
+	}
+
+	events, err := openEventStream(opts.JSONLines)
 	if err != nil {
-		fmt.Println("Failed to create download directory:", err)
-		return
+		return fmt.Errorf("failed to open event stream: %w", err)
+	}
+	defer events.Close()
+
+	// With --json or events on stdout, stdout carries nothing but the JSON, so
+	// every other message goes to stderr.
+	if opts.JSON || opts.eventsOnStdout() {
+		output = os.Stderr
+	}
+
+	if !opts.List && !opts.JSON && !opts.Outdated {
+		// The banner is for people, logs of piped output don't need it.
+		if !opts.NoBanner && isTerminal(output) {
+			printBanner()
+		}
+		if opts.Dequarantine {
+			warnDequarantine()
+		}
+		if !opts.Yes && !waitForEnter() {
+			return nil
+		}
+	}
+
+	downloadPath, err := installDir(opts)
+	if err != nil {
+		return fmt.Errorf("failed to resolve install directory: %w", err)
+	}
+
+	if !opts.readOnly() {
+		err = os.MkdirAll(downloadPath, 0755)
+		if err != nil {
+			return fmt.Errorf("failed to create download directory: %w", err)
+		}
+		sweepStaged(downloadPath)
 	}
 
-	repos := strings.Split(string(data), "\n")
+	if !opts.NoCache {
+		cache = loadCache(filepath.Join(downloadPath, CacheFile), opts.CacheTTL)
+		if !opts.readOnly() {
+			defer func() {
+				if err := cache.save(); err != nil {
+					console.errorf("Failed to save API cache: %v", err)
+				}
+			}()
+		}
+	}
 
-	type appInfo struct {
-		Name        string
-		Description string
-		DownloadURL string
+	// cancel is what an interrupt calls, the timeout sits below it so both
+	// stop the run.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if opts.InstallTimeout > 0 {
+		var cancelTimeout context.CancelFunc
+		ctx, cancelTimeout = context.WithTimeout(ctx, opts.InstallTimeout)
+		defer cancelTimeout()
 	}
+
 	var availableApps []appInfo
+	var unmatched []unmatchedRepo
+	var invalid []string
+	var listed []repoEntry
+	failed := make(map[string]bool)
+	if opts.FromResolved {
+		availableApps, err = loadResolved(downloadPath, opts.ResolvedTTL)
+		if err != nil {
+			return fmt.Errorf("failed to load resolved apps: %w", err)
+		}
+	} else if opts.LocalSource != "" {
+		availableApps, unmatched = discoverLocal(opts.LocalSource)
+	} else {
+		listPath := reposListPath(opts)
+		var entries []repoEntry
+		if opts.FromLock != "" {
+			listPath = opts.FromLock
+			entries, err = loadLockFile(opts.FromLock)
+			if err != nil {
+				return exitErr(ExitRepoList, fmt.Errorf("failed to read lockfile: %w", err))
+			}
+		} else {
+			entries, invalid, err = loadRepoList(ctx, opts.API, listPath, opts.RepoFileFormat, opts.Profile)
+			if err != nil {
+				return exitErr(ExitRepoList, fmt.Errorf("failed to read repos list file: %w", err))
+			}
+		}
+		if len(entries) == 0 {
+			printInvalid(invalid)
+			if listPath == StdinRepoList {
+				listPath = "The repo list on stdin"
+			}
+			return exitErr(ExitRepoList, fmt.Errorf("%s doesn't list any repos. Add one owner/repo per line, for example:\n\n    donuts-are-good/checksum", listPath))
+		}
+		listed = entries
+		if len(opts.Names) > 0 {
+			entries = namedEntries(entries, opts.Names)
+		}
+		if opts.RetryFailed {
+			lastFailed, err := loadFailed(downloadPath)
+			if err != nil {
+				return fmt.Errorf("failed to read the apps that failed last run: %w", err)
+			}
+			entries = onlyFailed(entries, lastFailed)
+			if len(entries) == 0 {
+				console.infof("Nothing failed in the last run, there is nothing to retry.")
+				return nil
+			}
+		}
+		if opts.Update && opts.IgnorePins {
+			entries = unpin(entries)
+		}
+		availableApps, unmatched = discoverApps(ctx, opts, entries, events)
+		failed = discoveryFailures(entries, availableApps, unmatched)
+		if !opts.readOnly() {
+			defer saveFailed(downloadPath, failed)
+		}
+	}
+	if opts.PrefixName != "" {
+		for i := range availableApps {
+			availableApps[i] = availableApps[i].prefixName(opts.PrefixName)
+		}
+	}
+	if len(opts.Names) > 0 {
+		var unknown []string
+		availableApps, unknown = namedApps(availableApps, opts.Names)
+		if len(unknown) > 0 && ctx.Err() == nil && len(failed) == 0 {
+			return exitErr(ExitUsage, fmt.Errorf("no app available for your platform is named %s", strings.Join(unknown, ", ")))
+		}
+	}
+	if ctx.Err() == context.DeadlineExceeded {
+		return exitErr(ExitNetwork, fmt.Errorf("looking up releases did not finish within %s, nothing was installed", opts.InstallTimeout))
+	}
+	if len(availableApps) == 0 && len(failed) > 0 {
+		return exitErr(ExitNetwork, fmt.Errorf("looking up all %d repo(s) failed, nothing was installed", len(failed)))
+	}
 
-	for _, repo := range repos {
-		repo = strings.TrimSpace(repo)
-		if repo == "" {
-			continue
+	if opts.Outdated {
+		if err := printOutdated(downloadPath, availableApps); err != nil {
+			return fmt.Errorf("failed to read install manifest: %w", err)
 		}
+		return nil
+	}
 
-		// Get repository description
-		repoInfoUrl := BaseURL + repo
-		resp, err := http.Get(repoInfoUrl)
+	upToDate, pinned := 0, 0
+	if opts.Update {
+		availableApps, upToDate, pinned, err = pendingUpdates(downloadPath, availableApps)
 		if err != nil {
-			fmt.Println("Failed to get repository info:", err)
-			continue
+			return fmt.Errorf("failed to read install manifest: %w", err)
 		}
-		if resp.StatusCode != 200 {
-			fmt.Println("Received non-200 response code when getting repository info:", resp.StatusCode)
-			continue
+		if len(availableApps) == 0 {
+			fmt.Fprintf(output, "\n%d up to date, 0 updated, %d skipped, %d failed\n", upToDate, pinned, len(failed))
+			return discoveryErr(failed, unmatched)
+		}
+		if opts.ShowNotes {
+			if err := printUpdateNotes(ctx, opts.API, downloadPath, availableApps); err != nil {
+				return fmt.Errorf("failed to read install manifest: %w", err)
+			}
 		}
-		defer resp.Body.Close()
-		body, err := io.ReadAll(resp.Body)
+	}
+
+	if opts.JSON {
+		err = printAppJSON(os.Stdout, downloadPath, availableApps)
 		if err != nil {
-			fmt.Println("Failed to read repository info response body:", err)
-			continue
+			return fmt.Errorf("failed to write JSON: %w", err)
 		}
-		var repoInfo struct {
-			Description string `json:"description"`
+		return nil
+	}
+	if opts.List {
+		printAppTable(availableApps)
+		return nil
+	}
+
+	// The list is needed to answer the prompt, so --quiet only hides it when
+	// nothing is asked.
+	if verbosity > LevelQuiet || (!opts.Update && !opts.Yes && !opts.Sync && len(opts.Names) == 0 && !opts.DryRun && !opts.ResolveOnly) {
+		fmt.Fprintln(output, "\n\n\nThe following applications are available for your system:")
+		for i, app := range availableApps {
+			note := ""
+			if app.Universal {
+				note = " (platform-agnostic)"
+			}
+			fmt.Fprintf(output, "\n%d. Name: %s%s\nDescription: %s\n", i+1, app.displayName(), note, app.Description)
 		}
-		err = json.Unmarshal(body, &repoInfo)
+		fmt.Fprintf(output, "\nTotal download: %s\n", formatBytes(totalSize(availableApps)))
+	}
+	printUnmatched(unmatched, opts.ReportUnmatched)
+	printInvalid(invalid)
+
+	if opts.DryRun {
+		printDryRun(downloadPath, availableApps)
+		return nil
+	}
+
+	if opts.ResolveOnly {
+		err = saveResolved(downloadPath, availableApps)
 		if err != nil {
-			fmt.Println("Failed to unmarshal repository info:", err)
-			continue
+			return fmt.Errorf("failed to save resolved apps: %w", err)
 		}
+		console.infof("\nSaved the resolved apps. Run again with --from-resolved to download them without looking them up again.")
+		return nil
+	}
 
-		repoUrl := BaseURL + repo + "/releases/latest"
-		resp, err = http.Get(repoUrl)
+	// Naming the apps answers the prompt, and sync installs them all.
+	selected := availableApps
+	if !opts.Update && !opts.Yes && !opts.Sync && len(opts.Names) == 0 {
+		selected, err = promptSelection(availableApps)
 		if err != nil {
-			fmt.Println("Failed to get latest release:", err)
-			continue
+			return fmt.Errorf("failed to read user input: %w", err)
 		}
-		if resp.StatusCode != 200 {
-			fmt.Println("Received non-200 response code:", resp.StatusCode)
-			continue
+	}
+	if limit := opts.ConfirmAbove << 20; limit > 0 && !opts.Yes && totalSize(selected) > limit {
+		if !confirmDownload(totalSize(selected), limit) {
+			console.infof("Nothing was downloaded.")
+			return nil
+		}
+	}
+
+	summary := newRunSummary(failed, unmatched)
+	for _, app := range availableApps {
+		if !containsApp(selected, app) {
+			summary.skip(app.displayName(), "not selected")
 		}
+	}
 
-		defer resp.Body.Close()
-		body, err = io.ReadAll(resp.Body)
+	var installErr error
+	if len(selected) > 0 {
+		inst := &installer{
+			opts:   opts,
+			dir:    downloadPath,
+			wd:     &watchdog{idle: opts.StallTimeout, retries: opts.StallRetries, policy: opts.Download, events: events, progress: showProgress()},
+			events: events,
+		}
+		var incomplete []string
+		selected = keepForeignFiles(opts, downloadPath, selected, summary)
+		ready := preflightAssets(ctx, opts.API, selected)
+		for _, app := range selected {
+			if !containsApp(ready, app) {
+				failed[app.Repo] = true
+				summary.fail(app.displayName(), "asset could not be reached")
+			}
+		}
+		var installed []appInfo
+		stopInterrupts := cancelOnInterrupt(cancel)
+		results := inst.installAll(ctx, ready)
+		stopInterrupts()
+		for i, err := range results {
+			if err == nil {
+				installed = append(installed, ready[i])
+				name := ready[i].displayName()
+				if verifier, _ := verifierFor(opts, ready[i].VerifyKey); verifier != nil && ready[i].SignatureURL == "" {
+					name += " (unsigned)"
+				}
+				summary.install(name)
+				continue
+			}
+			if errors.Is(err, errUpToDate) {
+				summary.skip(ready[i].displayName(), err.Error())
+				continue
+			}
+			failed[ready[i].Repo] = true
+			summary.fail(ready[i].displayName(), err.Error())
+			if ctx.Err() != nil {
+				incomplete = append(incomplete, ready[i].Name)
+			}
+		}
+		recordInstalled(downloadPath, opts.Profile, installed)
+		inst.wd.printReport()
+		summary.print()
+		if summary.hasFailures() {
+			installErr = exitErr(ExitPartial, fmt.Errorf("%d app(s) failed to install", len(summary.failed)+len(summary.refused)))
+		}
+		if opts.Update {
+			fmt.Fprintf(output, "\n%d up to date, %d updated, %d skipped, %d failed\n", upToDate, len(installed), pinned, len(failed))
+		}
+		if ctx.Err() != nil {
+			if len(incomplete) > 0 {
+				console.errorf("\nThe following apps did not complete:")
+				for _, name := range incomplete {
+					console.errorf("  %s", name)
+				}
+			}
+			if interrupted.Load() {
+				return exitErr(ExitInterrupted, errors.New("interrupted, apps that hadn't finished downloading were not installed"))
+			}
+			return exitErr(ExitPartial, fmt.Errorf("the install did not finish within %s", opts.InstallTimeout))
+		}
+	}
+	// Install failures were counted above, what's left are the repos whose
+	// release couldn't be looked up or was refused.
+	if installErr == nil {
+		installErr = discoveryErr(failed, unmatched)
+	}
+	if opts.Sync && listed != nil {
+		pruneProfile(downloadPath, opts.Profile, listed)
+	}
+	if opts.Scope == ScopeProject {
+		writeActivateScript(downloadPath)
+	} else if opts.OS != "" || opts.Arch != "" {
+		console.infof("\nStaged binaries for %s/%s in %s, skipping PATH changes since they're meant for another machine.", targetOS, targetArch, downloadPath)
+	} else if opts.GracePeriod {
+		printDeferredPath(downloadPath)
+	} else {
+		if addToPath(downloadPath) {
+			console.infof("You will need to restart your terminal or source your shell profile for the changes to take effect.")
+		}
+	}
+	return installErr
+}
+
+func printBanner() {
+	console.infof("%s", `     _                   _   
+  __| | ___  _ __  _   _| |_ 
+ / _' |/ _ \| '_ \| | | | __|
+| (_| | (_) | | | | |_| | |_ 
+ \__,_|_____|_| |_|\__,_|\__|
+ _   _| |_(_| |___           
+| | | | __| | / __|          
+| |_| | |_| | \__ \          
+ \__,_|\__|_|_|___/          
+                             `)
+	console.infof("donut-utils is a collection of cli utilities focusing on convenience and human readable output.\n\nThe applications will be downloaded from Github, and placed in ~/.donut-utils and then ~/.donut-utils will be added to your path.\n\nfor more information, visit the url below:\nhttps://github.com/donuts-are-good/donut-utils")
+}
+
+func discoverApps(ctx context.Context, opts options, entries []repoEntry, events *eventStream) ([]appInfo, []unmatchedRepo) {
+	var availableApps []appInfo
+	var unmatched []unmatchedRepo
+	collect := func(app *appInfo, missing *unmatchedRepo) {
+		if app != nil {
+			availableApps = append(availableApps, *app)
+		}
+		if missing != nil {
+			unmatched = append(unmatched, *missing)
+		}
+	}
+
+	var batched map[string]*releaseInfo
+	if token := githubToken(); token != "" && opts.GraphQL {
+		var err error
+		batched, err = fetchReleasesGraphQL(ctx, opts.API, token, entries)
 		if err != nil {
-			fmt.Println("Failed to read response body:", err)
+			console.errorf("Failed to look up releases over GraphQL, falling back to REST: %v", err)
+		}
+	}
+
+	// handleRateLimit waits out a short rate limit so the repo can be retried,
+	// or reports the remaining repos so a later run can pick them up.
+	handleRateLimit := func(limited *rateLimitError, remaining int) bool {
+		if waitForReset(ctx, limited, opts.MaxRateWait) {
+			return true
+		}
+		console.errorf("%s", red(fmt.Sprintf("%s, %d repo(s) were not looked up.", limited, remaining)))
+		console.errorf("Apps found so far can still be installed. Run again with --retry-failed after the reset to finish the rest.")
+		return false
+	}
+
+	for i := 0; i < len(entries); i++ {
+		if ctx.Err() != nil {
+			break
+		}
+		entry := entries[i]
+		repo := entry.Repo
+
+		events.emit(event{Type: EventResolveStarted, Repo: repo})
+
+		if release, ok := batched[repo]; ok && entry.Version == "" {
+			collect(matchRelease(ctx, opts, entry, *release, events))
 			continue
 		}
 
-		var release struct {
-			Assets []struct {
-				Name               string `json:"name"`
-				BrowserDownloadUrl string `json:"browser_download_url"`
-			} `json:"assets"`
+		// Get repository description
+		repoInfo, err := fetchRepoInfo(ctx, opts.API, repo)
+		var limited *rateLimitError
+		if errors.As(err, &limited) {
+			events.fail(repo, "", err)
+			if handleRateLimit(limited, len(entries)-i) {
+				i--
+				continue
+			}
+			break
+		}
+		if err != nil {
+			if entry.Description == "" && entry.Name == "" {
+				console.errorf("Failed to get repository info: %v", err)
+				events.fail(repo, "", err)
+				continue
+			}
+			console.infof("Could not get repository info for %s, using the name and description from the repo list: %v", repo, err)
 		}
 
-		err = json.Unmarshal(body, &release)
+		release, err := fetchRelease(ctx, opts.API, entry)
+		if errors.As(err, &limited) {
+			events.fail(repo, "", err)
+			if handleRateLimit(limited, len(entries)-i) {
+				i--
+				continue
+			}
+			break
+		}
 		if err != nil {
-			fmt.Println("Failed to unmarshal release info:", err)
+			console.errorf("Failed to get latest release: %v", err)
+			events.fail(repo, "", err)
 			continue
 		}
 
-		for _, asset := range release.Assets {
-			if strings.Contains(asset.Name, runtime.GOOS) && strings.Contains(asset.Name, runtime.GOARCH) {
-				availableApps = append(availableApps, appInfo{
-					Name:        asset.Name,
-					Description: repoInfo.Description,
-					DownloadURL: asset.BrowserDownloadUrl,
-				})
-				break
-			}
+		release.Description = repoInfo.Description
+		release.DefaultBranch = repoInfo.DefaultBranch
+		collect(matchRelease(ctx, opts, entry, release, events))
+	}
+
+	return availableApps, unmatched
+}
+
+type repoDetails struct {
+	Description   string `json:"description"`
+	DefaultBranch string `json:"default_branch"`
+}
+
+func fetchRepoInfo(ctx context.Context, policy retryPolicy, repo string) (repoDetails, error) {
+	if project, ok := gitLabProject(repo); ok {
+		return fetchGitLabRepoInfo(ctx, policy, project)
+	}
+	var info repoDetails
+	resp, err := policy.githubGet(ctx, repoAPI(repo, ""))
+	if err != nil {
+		return info, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		return info, fmt.Errorf("received non-200 response code: %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return info, err
+	}
+	err = json.Unmarshal(body, &info)
+	return info, err
+}
+
+// fetchRelease looks up the release an entry asks for, the latest one unless
+// it pins a tag.
+func fetchRelease(ctx context.Context, policy retryPolicy, entry repoEntry) (releaseInfo, error) {
+	if project, ok := gitLabProject(entry.Repo); ok {
+		return fetchGitLabRelease(ctx, policy, project, entry)
+	}
+	repoUrl := repoAPI(entry.Repo, "/releases/latest")
+	if entry.Version != "" {
+		repoUrl = repoAPI(entry.Repo, "/releases/tags/"+url.PathEscape(entry.Version))
+	}
+
+	resp, err := policy.githubGet(ctx, repoUrl)
+	if err != nil {
+		return releaseInfo{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		return releaseInfo{}, fmt.Errorf("received non-200 response code: %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return releaseInfo{}, err
+	}
+
+	var release struct {
+		TagName string `json:"tag_name"`
+		Author  struct {
+			Login string `json:"login"`
+		} `json:"author"`
+		Assets    []releaseAsset `json:"assets"`
+		AssetsURL string         `json:"assets_url"`
+	}
+	err = json.Unmarshal(body, &release)
+	if err != nil {
+		return releaseInfo{}, err
+	}
+	if len(release.Assets) >= DefaultPageSize && release.AssetsURL != "" {
+		release.Assets, err = fetchAssets(ctx, policy, release.AssetsURL)
+		if err != nil {
+			return releaseInfo{}, err
 		}
 	}
 
-	fmt.Println("\n\n\nThe following applications are available for your system:")
-	for i, app := range availableApps {
-		fmt.Printf("\n%d. Name: %s\nDescription: %s\n", i+1, app.Name, app.Description)
+	return releaseInfo{
+		Author:  release.Author.Login,
+		Version: release.TagName,
+		Assets:  release.Assets,
+	}, nil
+}
+
+// matchRelease picks the asset to install from a repo's resolved release.
+// An exact asset or pattern from the repo list wins over the repo's own
+// metadata, which wins over platform matching. It returns nil for both when
+// the release was refused outright.
+func matchRelease(ctx context.Context, opts options, entry repoEntry, release releaseInfo, events *eventStream) (*appInfo, *unmatchedRepo) {
+	repo, exactAsset := entry.Repo, entry.ExactAsset
+
+	metadata := release.Metadata
+	if !release.MetadataLoaded {
+		var err error
+		metadata, err = fetchRepoMetadata(ctx, opts.API, repo, release.DefaultBranch)
+		if err != nil {
+			console.errorf("Failed to read %s for %s, falling back to asset matching: %v", RepoMetadataFile, repo, err)
+		}
 	}
-	fmt.Println("\n\nDo you want to download these applications? (yes/no)")
 
-	reader := bufio.NewReader(os.Stdin)
-	response, err := reader.ReadString('\n')
+	if !opts.TrustedAuthors.allows(repo, release.Author) {
+		console.errorf("%s", red(fmt.Sprintf("Refusing to install %s: latest release was published by %q, who is not a trusted author", repo, release.Author)))
+		err := fmt.Errorf("release published by untrusted author %q", release.Author)
+		events.fail(repo, "", err)
+		return nil, &unmatchedRepo{Repo: repo, Refused: err.Error()}
+	}
+
+	verifier, err := verifierFor(opts, entry.VerifyKey)
 	if err != nil {
-		fmt.Println("Failed to read user input:", err)
-		return
+		console.errorf("%s", red(fmt.Sprintf("Refusing to install %s: failed to read its verify_key: %v", repo, err)))
+		events.fail(repo, "", err)
+		return nil, nil
 	}
 
-	response = strings.ToLower(strings.TrimSpace(response))
-	if response == "yes" {
-		for _, app := range availableApps {
-			downloadAndStore(app.DownloadURL, downloadPath)
+	var assetNames []string
+	for _, asset := range release.Assets {
+		assetNames = append(assetNames, asset.Name)
+	}
+
+	// A filter narrows the assets platform matching chooses from.
+	assets := release.Assets
+	if entry.Filter != "" && exactAsset == "" {
+		assets = filterAssets(assets, func(name string) bool { return matchesAssetPattern(entry.Filter, name) })
+		if len(assets) == 0 {
+			console.errorf("None of the assets in the latest release of %s matches %q from the repo list", repo, entry.Filter)
+			events.fail(repo, "", fmt.Errorf("no asset matches filter %q", entry.Filter))
+			return nil, &unmatchedRepo{Repo: repo, Assets: assetNames}
 		}
 	}
-	if runtime.GOOS == "windows" {
-		fmt.Println("Please add the following directory to your PATH manually in Windows:")
-		fmt.Println(downloadPath)
-		fmt.Println("You may need to restart your terminal or system for changes to take effect.")
-	} else {
-		addToPath(downloadPath)
-		fmt.Println("You will need to restart your terminal or source your shell profile for the changes to take effect.")
-		fmt.Println("If you're using bash or zsh, you can do this by running one of the following commands:")
-		fmt.Println("\nFor bash: source ~/.bashrc")
-		fmt.Println("For zsh:  source ~/.zshrc")
+
+	console.debugf("%s: release %s has %d asset(s)", repo, release.Version, len(release.Assets))
+	var asset releaseAsset
+	var ok bool
+	var pickedBy string
+	var universal bool
+	switch {
+	case exactAsset != "":
+		pickedBy = "its exact name in the repo list"
+		matches := filterAssets(release.Assets, func(name string) bool { return name == exactAsset })
+		if len(matches) > 0 {
+			asset, ok = matches[0], true
+		}
+	case entry.Asset != "":
+		pickedBy = fmt.Sprintf("the pattern %q in the repo list", entry.Asset)
+		asset, ok = bestAsset(filterAssets(assets, func(name string) bool { return matchesAssetPattern(entry.Asset, name) }))
+	case metadata != nil && metadata.Asset != "":
+		pickedBy = fmt.Sprintf("the pattern %q in %s", metadata.Asset, RepoMetadataFile)
+		asset, ok = bestAsset(filterAssets(assets, metadata.matchesAsset))
+	default:
+		pickedBy = "matching " + targetOS + "/" + targetArch
+		asset, ok = selectAsset(assets, targetOS, targetArch)
+		if !ok {
+			pickedBy = "falling back to a platform-agnostic asset"
+			asset, ok = universalAsset(assets, opts.UniversalAsset)
+			universal = ok
+		}
+	}
+	if ok {
+		console.debugf("%s: picked %s by %s", repo, asset.Name, pickedBy)
+		app := appInfo{
+			Repo:         repo,
+			Version:      release.Version,
+			Pinned:       entry.Version != "",
+			LockedSHA256: entry.LockedSHA256,
+			Name:         asset.Name,
+			DisplayName:  entry.Name,
+			Description:  release.Description,
+			DownloadURL:  asset.BrowserDownloadUrl,
+			AssetURL:     asset.URL,
+			Size:         asset.Size,
+			Universal:    universal,
+		}
+		if metadata != nil {
+			app.BinaryName = metadata.Binary
+		}
+		if entry.Alias != "" {
+			app.BinaryName = entry.Alias
+		}
+		if app.Description == "" {
+			app.Description = entry.Description
+		}
+		app.InstallDir = entry.InstallDir
+		if checksumName := checksumAssetFor(asset.Name, assetNames); checksumName != "" {
+			for _, candidate := range release.Assets {
+				if candidate.Name == checksumName {
+					app.ChecksumName = candidate.Name
+					app.ChecksumURL = candidate.BrowserDownloadUrl
+				}
+			}
+		}
+		app.VerifyKey = entry.VerifyKey
+		app.Extract = entry.Extract
+		if verifier != nil {
+			if signature, ok := signatureAsset(verifier, asset.Name, release.Assets); ok {
+				app.SignatureName = signature.Name
+				app.SignatureURL = signature.BrowserDownloadUrl
+			}
+		}
+		events.emit(event{Type: EventAssetMatched, Repo: repo, App: app.Name})
+		return &app, nil
+	}
+
+	if exactAsset != "" {
+		console.errorf("Asset %q named in the repo list was not found in the latest release of %s", exactAsset, repo)
+		events.fail(repo, "", fmt.Errorf("asset %q not found in release", exactAsset))
 	}
+	return nil, &unmatchedRepo{Repo: repo, Assets: assetNames}
+}
+
+// installer carries the state shared by every download in a run.
+type installer struct {
+	opts   options
+	dir    string
+	wd     *watchdog
+	events *eventStream
 }
-func downloadAndStore(url string, downloadPath string) {
-	resp, err := http.Get(url)
+
+// downloadAndStore installs one app, writing its messages to out so parallel
+// downloads don't interleave. The error says why the app wasn't installed.
+func (in *installer) downloadAndStore(ctx context.Context, app appInfo, worker int, out io.Writer) error {
+	events := in.events
+	log := logger{out: out}
+	downloadPath := in.dir
+	if app.InstallDir != "" {
+		downloadPath = app.InstallDir
+		if err := os.MkdirAll(downloadPath, 0755); err != nil {
+			log.errorf("%s", red(fmt.Sprint("Failed to create install directory: ", err)))
+			events.fail(app.Repo, app.Name, err)
+			return err
+		}
+	}
+
+	target := app.installPath(in.dir)
+	ext := archiveExt(app.Name)
+
+	if in.alreadyInstalled(ctx, app, target, log) {
+		log.infof("%s is already up to date in %s", app.Name, target)
+		return errUpToDate
+	}
+
+	// The download is staged next to the target and only renamed over it once
+	// it has been verified, so a failed run never leaves a broken binary on
+	// the PATH. Whatever is left of the staged files is removed on return, and
+	// sweepStaged removes what a killed run couldn't.
+	sweepStaged(filepath.Dir(target))
+	tmp, err := os.CreateTemp(filepath.Dir(target), StagedPrefix+filepath.Base(target)+"-*"+ext)
 	if err != nil {
-		fmt.Println("Failed to download file:", err)
-		return
+		log.errorf("%s", red(fmt.Sprint("Failed to create temporary file: ", err)))
+		events.fail(app.Repo, app.Name, err)
+		return err
 	}
-	defer resp.Body.Close()
+	tmp.Close()
+	downloaded := tmp.Name()
+	defer os.Remove(downloaded)
 
-	filename := filepath.Base(url)
-	index := strings.Index(filename, "-v")
-	if index == -1 {
-		fmt.Println("Invalid filename format, cannot find version:", filename)
-		return
+	if app.LocalPath != "" {
+		err = copyLocal(app.LocalPath, downloaded)
+	} else {
+		err = in.wd.download(ctx, app, worker, downloaded, log)
+	}
+	if err != nil {
+		log.errorf("%s", red(fmt.Sprint("Failed to download file: ", err)))
+		events.fail(app.Repo, app.Name, err)
+		return err
 	}
 
-	appName := filename[:index]
-	out, err := os.Create(filepath.Join(downloadPath, appName))
+	verifier, err := verifierFor(in.opts, app.VerifyKey)
 	if err != nil {
-		fmt.Println("Failed to create file:", err)
-		return
+		log.errorf("%s", red(fmt.Sprintf("Failed to read the key to verify %s with: %v", app.Name, err)))
+		events.fail(app.Repo, app.Name, err)
+		return err
 	}
-	defer out.Close()
 
-	_, err = io.Copy(out, resp.Body)
+	// A signature vouches for the download just as well as a checksum.
+	signed := verifier != nil && app.SignatureURL != ""
+	expected, pinned := in.opts.PinnedChecksums[app.Repo+"@"+app.Version]
+	if !pinned && !signed && app.ChecksumURL == "" && app.LocalPath == "" && app.LockedSHA256 == "" {
+		if in.opts.RequireChecksum {
+			err = errNoChecksum
+			log.errorf("%s", red(fmt.Sprintf("Refusing to install %s, its release publishes no checksum and none is pinned", app.Name)))
+			events.fail(app.Repo, app.Name, err)
+			return err
+		}
+		log.infof("%s", yellow(fmt.Sprintf("Warning: %s has no checksum to verify it against, installing it unverified", app.Name)))
+	}
+
+	if pinned {
+		used, err := verifyDigest(downloaded, expected, detectAlgos(AlgoAuto, "", expected), log)
+		if err != nil {
+			log.errorf("%s", red(fmt.Sprintf("Failed to verify %s against the pinned checksum, removed it: %v", app.Name, err)))
+			events.fail(app.Repo, app.Name, err)
+			return err
+		}
+		log.infof("%s", green(fmt.Sprintf("Verified pinned %s checksum of %s", used, app.Name)))
+	}
+
+	if app.ChecksumURL != "" {
+		used, err := verifyChecksumAsset(ctx, in.opts.API, app, downloaded, in.opts.ChecksumAlgo, log)
+		if err != nil {
+			log.errorf("%s", red(fmt.Sprintf("Failed to verify %s, removed it: %v", app.Name, err)))
+			events.fail(app.Repo, app.Name, err)
+			return err
+		}
+		log.infof("%s", green(fmt.Sprintf("Verified %s checksum of %s", used, app.Name)))
+	}
+
+	if verifier != nil && app.SignatureURL == "" {
+		log.infof("%s", yellow(fmt.Sprintf("Warning: %s is unsigned, installing it without checking a signature", app.Name)))
+	}
+	if signed {
+		err = verifySignature(ctx, in.opts.API, verifier, app, downloaded)
+		if err != nil {
+			log.errorf("%s", red(fmt.Sprintf("Failed to verify the signature of %s, removed it: %v", app.Name, err)))
+			events.fail(app.Repo, app.Name, err)
+			return err
+		}
+		log.infof("%s", green(fmt.Sprintf("Verified %s against %s", app.Name, app.SignatureName)))
+	}
+
+	staged := downloaded
+	if ext != "" {
+		staged = strings.TrimSuffix(downloaded, ext)
+		defer os.Remove(staged)
+		err = extractBinary(downloaded, ext, filepath.Base(target), app.Extract, staged)
+		if err != nil {
+			log.errorf("%s", red(fmt.Sprintf("Failed to extract %s: %v", app.Name, err)))
+			events.fail(app.Repo, app.Name, err)
+			return err
+		}
+	}
+
+	err = os.Chmod(staged, 0755)
 	if err != nil {
-		fmt.Println("Failed to write file:", err)
-		return
+		log.errorf("%s", red(fmt.Sprint("Failed to change file permissions: ", err)))
+		events.fail(app.Repo, app.Name, err)
+		return err
+	}
+
+	if in.opts.Dequarantine {
+		err = removeQuarantine(staged)
+		if err != nil {
+			log.errorf("%s", red(fmt.Sprint("Failed to remove quarantine attribute: ", err)))
+		}
+	}
+
+	if app.LockedSHA256 != "" {
+		if _, err := verifyDigest(staged, app.LockedSHA256, []string{AlgoSHA256}, log); err != nil {
+			log.errorf("%s", red(fmt.Sprintf("Refusing to install %s, it isn't the binary the lockfile records: %v", app.Name, err)))
+			events.fail(app.Repo, app.Name, err)
+			return err
+		}
+		log.infof("%s", green(fmt.Sprintf("Verified %s against the lockfile", app.Name)))
 	}
 
-	err = os.Chmod(filepath.Join(downloadPath, appName), 0755)
+	err = os.Rename(staged, target)
 	if err != nil {
-		fmt.Println("Failed to change file permissions:", err)
-		return
+		log.errorf("%s", red(fmt.Sprint("Failed to move the download into place: ", err)))
+		events.fail(app.Repo, app.Name, err)
+		return err
 	}
 
-	fmt.Println("File downloaded and saved to:", filepath.Join(downloadPath, appName))
+	log.infof("%s", green("File downloaded and saved to: "+target))
+	events.emit(event{Type: EventInstallComplete, Repo: app.Repo, App: app.Name, Path: target})
+	return nil
 }
 
-func addToPath(dir string) {
-	shell := os.Getenv("SHELL")
-	var shellrc string
+const (
+	ShellBash       = "bash"
+	ShellZsh        = "zsh"
+	ShellFish       = "fish"
+	ShellPowerShell = "powershell"
+)
 
-	if strings.Contains(shell, "bash") {
-		shellrc = ".bashrc"
-	} else if strings.Contains(shell, "zsh") {
-		shellrc = ".zshrc"
-	} else {
-		fmt.Println("Unsupported shell. Please add the following directory to your PATH manually:")
-		fmt.Println(dir)
-		return
+// shellProfiles maps each supported shell to its profile, relative to the
+// home directory.
+var shellProfiles = map[string]string{
+	ShellBash:       ".bashrc",
+	ShellZsh:        ".zshrc",
+	ShellFish:       ".config/fish/config.fish",
+	ShellPowerShell: "Documents/WindowsPowerShell/Microsoft.PowerShell_profile.ps1",
+}
+
+func detectShell() string {
+	if runtime.GOOS == "windows" {
+		return ShellPowerShell
+	}
+	shell := filepath.Base(os.Getenv("SHELL"))
+	for _, name := range []string{ShellFish, ShellBash, ShellZsh} {
+		if strings.Contains(shell, name) {
+			return name
+		}
 	}
+	return ""
+}
+
+func shellrcName() string {
+	return filepath.FromSlash(shellProfiles[detectShell()])
+}
+
+func pathExportLine(dir string) string {
+	return "export PATH=$PATH:" + dir
+}
 
-	usr, err := user.Current()
+func shellExportLine(shell, dir string) string {
+	switch shell {
+	case ShellFish:
+		return "set -gx PATH $PATH " + dir
+	case ShellPowerShell:
+		return `$env:Path += ";` + dir + `"`
+	}
+	return pathExportLine(dir)
+}
+
+func sourceCommand(shell, shellrc string) string {
+	if shell == ShellPowerShell {
+		return ". $PROFILE"
+	}
+	return "source ~/" + filepath.ToSlash(shellrc)
+}
+
+func applyPath(opts options) error {
+	downloadPath, err := installDir(opts)
 	if err != nil {
-		fmt.Println("Failed to get current user:", err)
+		return fmt.Errorf("failed to resolve install directory: %w", err)
+	}
+
+	if _, err := os.Stat(downloadPath); err != nil {
+		return fmt.Errorf("install directory not found, run donut-utils first: %w", err)
+	}
+
+	if opts.Scope == ScopeProject {
+		writeActivateScript(downloadPath)
+		return nil
+	}
+
+	addToPath(downloadPath)
+	return nil
+}
+
+func printDeferredPath(dir string) {
+	console.infof("\nSkipping PATH changes because --grace-period was set.")
+	shellrc := shellrcName()
+	if shellrc == "" {
+		console.errorf("Your shell is not supported, so you will need to add the following directory to your PATH manually:")
+		console.errorf("%s", dir)
 		return
 	}
+	console.infof("Once you've verified the install, the following line will be appended to ~/%s:", shellrc)
+	console.infof("\n    %s\n", shellExportLine(detectShell(), dir))
+	console.infof("To apply it, run:")
+	console.infof("\n    donut-utils apply-path")
+}
+
+// addToPath appends dir to the PATH in the shell profile, and reports
+// whether the profile was changed.
+func addToPath(dir string) bool {
+	if onPath(dir) {
+		console.infof("%s", green("Install dir already on PATH, leaving your shell profile alone: "+dir))
+		return false
+	}
 
-	shellrcPath := filepath.Join(usr.HomeDir, shellrc)
-	file, err := os.OpenFile(shellrcPath, os.O_APPEND|os.O_WRONLY, 0644)
+	shell := detectShell()
+	shellrc := shellrcName()
+	if shellrc == "" {
+		console.errorf("Unsupported shell. Please add the following directory to your PATH manually:")
+		console.errorf("%s", dir)
+		return false
+	}
+
+	shellrcPath, err := expandPath(filepath.Join("~", shellrc))
 	if err != nil {
-		fmt.Println("Failed to open shellrc file:", err)
-		return
+		console.errorf("Failed to find your home directory: %v", err)
+		return false
+	}
+	configured, err := pathAlreadyConfigured(shellrcPath, dir)
+	if err != nil {
+		console.errorf("Failed to read shellrc file: %v", err)
+		return false
+	}
+	if configured {
+		console.infof("%s", green(dir+" is already on your PATH in "+shellrc))
+		return false
+	}
+
+	err = os.MkdirAll(filepath.Dir(shellrcPath), 0755)
+	if err != nil {
+		console.errorf("Failed to create shellrc directory: %v", err)
+		return false
+	}
+
+	file, err := os.OpenFile(shellrcPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		console.errorf("Failed to open shellrc file: %v", err)
+		return false
 	}
 
 	defer file.Close()
 
-	_, err = file.WriteString("\nexport PATH=$PATH:" + dir)
+	_, err = file.WriteString("\n" + shellExportLine(shell, dir) + " " + PathMarker)
 	if err != nil {
-		fmt.Println("Failed to write to shellrc file:", err)
-		return
+		console.errorf("Failed to write to shellrc file: %v", err)
+		return false
 	}
 
-	fmt.Println("Successfully added to PATH in", shellrc)
-	fmt.Println("\nTo update your current session, please run the following command:")
-	fmt.Printf("\nsource ~/%s\n\n", shellrc)
+	console.infof("%s", green("Successfully added to PATH in "+shellrc))
+	console.infof("\nTo update your current session, please run the following command:")
+	console.infof("\n%s\n", sourceCommand(shell, shellrc))
+	return true
 }
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const ManifestFile = "installed.json"
+
+type installedApp struct {
+	Name        string    `json:"name,omitempty"`
+	Repo        string    `json:"repo,omitempty"`
+	Asset       string    `json:"asset"`
+	URL         string    `json:"url,omitempty"`
+	Version     string    `json:"version,omitempty"`
+	Path        string    `json:"path"`
+	SHA256      string    `json:"sha256,omitempty"`
+	Profile     string    `json:"profile,omitempty"`
+	InstalledAt time.Time `json:"installed_at"`
+}
+
+// manifest is what earlier runs installed, keyed by the installed file's
+// path. Knowing the installed versions is what lets later runs tell what's
+// out of date.
+type manifest map[string]installedApp
+
+func loadManifest(dir string) (manifest, error) {
+	installed := make(manifest)
+	data, err := os.ReadFile(filepath.Join(dir, ManifestFile))
+	if os.IsNotExist(err) {
+		return installed, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(data, &installed)
+	return installed, err
+}
+
+func (m manifest) save(dir string) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, ManifestFile), data, 0644)
+}
+
+// recordInstalled adds this run's apps to the manifest, keeping the entries
+// of apps it didn't touch. The digest of each installed file lets later runs
+// tell whether it was changed since, and the profile which apps sync and
+// remove --profile look after.
+func recordInstalled(dir, profile string, apps []appInfo) {
+	installed, err := loadManifest(dir)
+	if err != nil {
+		console.errorf("Failed to read install manifest: %v", err)
+		return
+	}
+	now := time.Now().UTC()
+	for _, app := range apps {
+		path := app.installPath(dir)
+		digest, err := fileDigest(path, AlgoSHA256)
+		if err != nil {
+			console.errorf("Failed to hash %s: %v", path, err)
+		}
+		url := app.DownloadURL
+		if app.LocalPath != "" {
+			url = app.LocalPath
+		}
+		installed[path] = installedApp{
+			Name:        app.displayName(),
+			Repo:        app.Repo,
+			Asset:       app.Name,
+			URL:         url,
+			Version:     app.Version,
+			Path:        path,
+			SHA256:      digest,
+			Profile:     profile,
+			InstalledAt: now,
+		}
+	}
+	err = installed.save(dir)
+	if err != nil {
+		console.errorf("Failed to save install manifest: %v", err)
+	}
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const RepoMetadataFile = ".donut-utils.yaml"
+
+// repoMetadata is the optional .donut-utils.yaml a repository can ship on its
+// default branch to tell the installer which asset to pick and what to call
+// the installed binary. Asset is a glob that may use {os} and {arch}.
+type repoMetadata struct {
+	Asset  string `yaml:"asset"`
+	Binary string `yaml:"binary"`
+}
+
+func fetchRepoMetadata(ctx context.Context, policy retryPolicy, repo, branch string) (*repoMetadata, error) {
+	if branch == "" {
+		return nil, nil
+	}
+
+	metadataUrl := repoAPI(repo, "/contents/"+RepoMetadataFile+"?ref="+url.QueryEscape(branch))
+	project, gitLab := gitLabProject(repo)
+	if gitLab {
+		metadataUrl = projectAPI(project, "/repository/files/"+url.PathEscape(RepoMetadataFile)+"/raw?ref="+url.QueryEscape(branch))
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, metadataUrl, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var resp *http.Response
+	if gitLab {
+		resp, err = policy.do(req)
+	} else {
+		req.Header.Set("Accept", "application/vnd.github.raw")
+		resp, err = policy.githubDo(req)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("received non-200 response code: %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	return parseRepoMetadata(body)
+}
+
+func parseRepoMetadata(body []byte) (*repoMetadata, error) {
+	var metadata repoMetadata
+	err := yaml.Unmarshal(body, &metadata)
+	if err != nil {
+		return nil, err
+	}
+	if metadata.Binary != "" {
+		metadata.Binary = filepath.Base(metadata.Binary)
+	}
+	return &metadata, nil
+}
+
+func (m *repoMetadata) matchesAsset(name string) bool {
+	return matchesAssetPattern(m.Asset, name)
+}
+
+// matchesAssetPattern matches an asset name against a glob that may use
+// {os} and {arch} placeholders for the current platform.
+func matchesAssetPattern(pattern, name string) bool {
+	pattern = strings.NewReplacer("{os}", targetOS, "{arch}", targetArch).Replace(pattern)
+	ok, err := filepath.Match(pattern, name)
+	return err == nil && ok
+}
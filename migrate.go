@@ -0,0 +1,258 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// bookkeepingFiles are the files donut-utils keeps next to the binaries,
+// which migrate never takes for one.
+var bookkeepingFiles = map[string]bool{
+	ManifestFile: true, FailedFile: true, ResolvedFile: true, ActivateScript: true, CacheFile: true,
+}
+
+// migrate brings an install directory from the flat layout of early
+// releases, binaries named after their asset with nothing recording them,
+// under installed.json so update, outdated and remove know about them. Each
+// binary is renamed to the name an install would give it, its version is
+// taken from the file name when it has one, and its repo from the repo
+// list. PATH lines written before they were marked get the marker. With
+// --dry-run it only reports what it would do.
+func migrate(opts options) error {
+	dir, err := installDir(opts)
+	if err != nil {
+		return fmt.Errorf("failed to resolve install directory: %w", err)
+	}
+	files, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		console.infof("Nothing to migrate, %s doesn't exist", dir)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read install directory: %w", err)
+	}
+	installed, err := loadManifest(dir)
+	if err != nil {
+		return fmt.Errorf("failed to read install manifest: %w", err)
+	}
+	repos := listedRepos(opts)
+
+	verb := "Adopting"
+	if opts.DryRun {
+		verb = "Would adopt"
+	}
+	adopted, failed := 0, 0
+	for _, file := range files {
+		path := filepath.Join(dir, file.Name())
+		if _, ok := installed[path]; ok || !isFlatBinary(file) {
+			continue
+		}
+		name := appInfo{Name: file.Name()}.binaryName()
+		target := filepath.Join(dir, name)
+		if _, err := os.Lstat(target); name == file.Name() || err == nil {
+			name, target = file.Name(), path
+		}
+		if _, ok := installed[target]; ok {
+			continue
+		}
+
+		app := installedApp{
+			Name:    name,
+			Repo:    repos[strings.ToLower(strings.TrimSuffix(name, ".exe"))],
+			Asset:   file.Name(),
+			Version: versionInName(file.Name()),
+			Path:    target,
+		}
+		from := ""
+		if target != path {
+			from = fmt.Sprintf(" from %s", file.Name())
+		}
+		console.infof("%s %s%s, %s", verb, target, from, describeAdopted(app))
+		if opts.DryRun {
+			adopted++
+			continue
+		}
+
+		if target != path {
+			if err := os.Rename(path, target); err != nil {
+				console.errorf("%s", red(fmt.Sprintf("Failed to rename %s to %s: %v", path, name, err)))
+				failed++
+				continue
+			}
+		}
+		info, err := os.Stat(target)
+		if err != nil {
+			console.errorf("%s", red(fmt.Sprintf("Failed to read %s: %v", target, err)))
+			failed++
+			continue
+		}
+		app.InstalledAt = info.ModTime().UTC()
+		if app.SHA256, err = fileDigest(target, AlgoSHA256); err != nil {
+			console.errorf("Failed to hash %s: %v", target, err)
+		}
+		installed[target] = app
+		adopted++
+	}
+
+	if adopted > 0 && !opts.DryRun {
+		if err := installed.save(dir); err != nil {
+			return fmt.Errorf("failed to save install manifest: %w", err)
+		}
+	}
+	pathErr := migratePath(opts, dir)
+	if adopted == 0 && failed == 0 {
+		console.infof("Nothing to migrate in %s, every binary is already recorded in %s", dir, ManifestFile)
+	}
+	if failed > 0 {
+		return exitErr(ExitPartial, fmt.Errorf("%d file(s) could not be migrated", failed))
+	}
+	return pathErr
+}
+
+// isFlatBinary reports whether file looks like a binary an early release
+// installed: an executable file that isn't hidden, like the staged
+// downloads, and isn't one of the bookkeeping files or a repo list.
+func isFlatBinary(file os.DirEntry) bool {
+	name := file.Name()
+	if !file.Type().IsRegular() || strings.HasPrefix(name, ".") || bookkeepingFiles[name] || strings.HasPrefix(name, "repolist") {
+		return false
+	}
+	if runtime.GOOS == "windows" {
+		return strings.HasSuffix(strings.ToLower(name), ".exe")
+	}
+	info, err := file.Info()
+	return err == nil && info.Mode().Perm()&0111 != 0
+}
+
+// versionInName is the version an asset name carries, like v1.2.3 in
+// tool-v1.2.3-linux-amd64, or "" when it has none.
+func versionInName(name string) string {
+	match := versionSuffix.FindStringSubmatch(strings.TrimSuffix(name, archiveExt(name)))
+	if match == nil {
+		return ""
+	}
+	return match[1]
+}
+
+// listedRepos maps the names apps of the repo list would be installed as,
+// their alias or their repo's name, to their repo. Without a readable repo
+// list it's empty and adopted apps have no repo.
+func listedRepos(opts options) map[string]string {
+	repos := make(map[string]string)
+	entries, _, err := loadRepoList(context.Background(), opts.API, reposListPath(opts), opts.RepoFileFormat, opts.Profile)
+	if err != nil {
+		console.debugf("No repo list to take repos from: %v", err)
+		return repos
+	}
+	for _, entry := range entries {
+		_, name := splitRepo(entry.Repo)
+		if entry.Alias != "" {
+			name = entry.Alias
+		}
+		repos[strings.ToLower(name)] = entry.Repo
+	}
+	return repos
+}
+
+func describeAdopted(app installedApp) string {
+	repo := "repo unknown, it's not in the repo list"
+	if app.Repo != "" {
+		repo = "from " + app.Repo
+	}
+	version := "version unknown"
+	if app.Version != "" {
+		version = "version " + app.Version
+	}
+	return repo + ", " + version
+}
+
+// migratePath marks the PATH lines early releases wrote for dir, which lack
+// PathMarker, so prune-path and uninstall look after them, and adds dir to
+// PATH when no profile has it. Projects have their activate script
+// instead.
+func migratePath(opts options, dir string) error {
+	if opts.Scope == ScopeProject {
+		if _, err := os.Stat(filepath.Join(dir, ActivateScript)); os.IsNotExist(err) {
+			if opts.DryRun {
+				console.infof("Would write %s", filepath.Join(dir, ActivateScript))
+				return nil
+			}
+			writeActivateScript(dir)
+		}
+		return nil
+	}
+
+	configured := false
+	failed := 0
+	for _, shell := range []string{ShellBash, ShellZsh, ShellFish, ShellPowerShell} {
+		shellrc := filepath.FromSlash(shellProfiles[shell])
+		shellrcPath, err := expandPath(filepath.Join("~", shellrc))
+		if err != nil {
+			return fmt.Errorf("failed to find your home directory: %w", err)
+		}
+		found, err := markPathLines(shellrcPath, dir, opts.DryRun)
+		if err != nil {
+			console.errorf("Failed to update %s: %v", shellrc, err)
+			failed++
+			continue
+		}
+		configured = configured || found
+	}
+	if failed > 0 {
+		return exitErr(ExitPartial, fmt.Errorf("%d shell profile(s) could not be updated", failed))
+	}
+
+	switch {
+	case configured || onPath(dir):
+	case opts.DryRun:
+		console.infof("Would add %s to your PATH", dir)
+	default:
+		addToPath(dir)
+	}
+	return nil
+}
+
+// markPathLines adds PathMarker to the lines of the shell profile at
+// shellrcPath that put dir on the PATH without it, and reports whether the
+// profile puts dir on the PATH at all.
+func markPathLines(shellrcPath, dir string, dryRun bool) (bool, error) {
+	info, err := os.Stat(shellrcPath)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	data, err := os.ReadFile(shellrcPath)
+	if err != nil {
+		return false, err
+	}
+
+	found, marked := false, 0
+	lines := strings.Split(string(data), "\n")
+	for i, line := range lines {
+		exported, hasMarker := exportedDir(line)
+		if exported == "" || resolvedDir(exported) != resolvedDir(dir) {
+			continue
+		}
+		found = true
+		if hasMarker {
+			continue
+		}
+		if dryRun {
+			console.infof("Would mark the PATH line in %s: %s", filepath.Base(shellrcPath), strings.TrimSpace(line))
+			continue
+		}
+		lines[i] = strings.TrimRight(line, " \t") + " " + PathMarker
+		console.infof("Marked the PATH line in %s: %s", filepath.Base(shellrcPath), strings.TrimSpace(line))
+		marked++
+	}
+	if marked == 0 {
+		return found, nil
+	}
+	return found, os.WriteFile(shellrcPath, []byte(strings.Join(lines, "\n")), info.Mode().Perm())
+}
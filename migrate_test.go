@@ -0,0 +1,113 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// flatInstall lays out what an early release left behind: binaries named
+// after their asset, one already recorded, a file that isn't a binary, and
+// an unmarked PATH line in ~/.bashrc. HOME is pointed at a temp directory.
+func flatInstall(t *testing.T) (dir, list, bashrc string) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("PATH", "/usr/bin")
+	dir = filepath.Join(home, DownloadDir)
+	if err := os.Mkdir(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]os.FileMode{"tool-v1.2.0-linux-amd64": 0o755, "other": 0o755, "kept": 0o755, "notes.txt": 0o644}
+	for name, mode := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(name), mode); err != nil {
+			t.Fatal(err)
+		}
+	}
+	kept := filepath.Join(dir, "kept")
+	if err := (manifest{kept: {Name: "kept", Repo: "owner/kept", Path: kept}}).save(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	list = filepath.Join(home, ReposList)
+	if err := os.WriteFile(list, []byte("owner/tool\nowner/kept\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	bashrc = filepath.Join(home, ".bashrc")
+	if err := os.WriteFile(bashrc, []byte("alias ll='ls -l'\nexport PATH=$PATH:"+dir+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return dir, list, bashrc
+}
+
+func TestMigrateAdoptsFlatBinaries(t *testing.T) {
+	dir, list, bashrc := flatInstall(t)
+
+	if err := migrate(options{InstallDir: dir, RepoList: list}); err != nil {
+		t.Fatal(err)
+	}
+	installed, err := loadManifest(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tool := installed[filepath.Join(dir, "tool")]
+	if tool.Repo != "owner/tool" || tool.Version != "v1.2.0" || tool.Asset != "tool-v1.2.0-linux-amd64" || tool.SHA256 == "" {
+		t.Errorf("tool was recorded as %+v", tool)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "tool")); err != nil {
+		t.Errorf("tool wasn't renamed: %v", err)
+	}
+	if other, ok := installed[filepath.Join(dir, "other")]; !ok || other.Repo != "" || other.Version != "" {
+		t.Errorf("other was recorded as %+v, %v", other, ok)
+	}
+	if _, ok := installed[filepath.Join(dir, "notes.txt")]; ok {
+		t.Error("notes.txt isn't a binary but was adopted")
+	}
+	if len(installed) != 3 {
+		t.Errorf("got %d apps in the manifest, want 3", len(installed))
+	}
+
+	data, err := os.ReadFile(bashrc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "export PATH=$PATH:" + dir + " " + PathMarker
+	if !strings.Contains(string(data), want+"\n") || strings.Count(string(data), dir) != 1 {
+		t.Errorf(".bashrc is\n%s\nwant the PATH line marked: %s", data, want)
+	}
+	if info, err := os.Stat(bashrc); err != nil || info.Mode().Perm() != 0o600 {
+		t.Errorf(".bashrc lost its mode: %v, %v", info, err)
+	}
+}
+
+func TestMigrateDryRunChangesNothing(t *testing.T) {
+	dir, list, bashrc := flatInstall(t)
+	before, err := os.ReadFile(bashrc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out := captureStdout(t, func() {
+		if err := migrate(options{InstallDir: dir, RepoList: list, DryRun: true}); err != nil {
+			t.Fatal(err)
+		}
+	})
+	for _, want := range []string{"Would adopt " + filepath.Join(dir, "tool") + " from tool-v1.2.0-linux-amd64", "Would mark the PATH line in .bashrc"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output is missing %q:\n%s", want, out)
+		}
+	}
+	installed, err := loadManifest(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(installed) != 1 {
+		t.Errorf("the manifest has %d apps, want only the one recorded before", len(installed))
+	}
+	if _, err := os.Stat(filepath.Join(dir, "tool-v1.2.0-linux-amd64")); err != nil {
+		t.Errorf("the binary was renamed: %v", err)
+	}
+	if after, err := os.ReadFile(bashrc); err != nil || string(after) != string(before) {
+		t.Errorf(".bashrc changed to\n%s", after)
+	}
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// releaseNote is the changelog of one release.
+type releaseNote struct {
+	Version string
+	Name    string
+	Body    string
+}
+
+// fetchNotes lists the releases of repo after from, up to and including to,
+// newest first. Pre-releases in between are left out.
+func fetchNotes(ctx context.Context, policy retryPolicy, repo, from, to string) ([]releaseNote, error) {
+	next := fmt.Sprintf("%s?per_page=%d", repoAPI(repo, "/releases"), MaxPageSize)
+	get := policy.githubGet
+	if project, ok := gitLabProject(repo); ok {
+		next = fmt.Sprintf("%s?per_page=%d", projectAPI(project, "/releases"), MaxPageSize)
+		get = policy.gitLabGet
+	}
+
+	var notes []releaseNote
+	for next != "" {
+		resp, err := get(ctx, next)
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode != 200 {
+			resp.Body.Close()
+			return nil, fmt.Errorf("listing releases returned response code %d", resp.StatusCode)
+		}
+
+		// GitHub calls the notes body and GitLab description.
+		var page []struct {
+			TagName     string `json:"tag_name"`
+			Name        string `json:"name"`
+			Body        string `json:"body"`
+			Description string `json:"description"`
+			Draft       bool   `json:"draft"`
+			Prerelease  bool   `json:"prerelease"`
+		}
+		err = json.NewDecoder(resp.Body).Decode(&page)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		reachedFrom := false
+		for _, release := range page {
+			if compareVersions(release.TagName, from) <= 0 {
+				reachedFrom = true
+				continue
+			}
+			if release.Draft || compareVersions(release.TagName, to) > 0 || (release.Prerelease && release.TagName != to) {
+				continue
+			}
+			body := release.Body
+			if body == "" {
+				body = release.Description
+			}
+			notes = append(notes, releaseNote{Version: release.TagName, Name: release.Name, Body: body})
+		}
+		if reachedFrom {
+			break
+		}
+		next = nextPage(resp)
+	}
+	return notes, nil
+}
+
+// printUpdateNotes prints, for every app about to be updated, the notes of
+// the releases since the installed version, for update --show-notes.
+func printUpdateNotes(ctx context.Context, policy retryPolicy, dir string, apps []appInfo) error {
+	installed, err := loadManifest(dir)
+	if err != nil {
+		return err
+	}
+	for _, app := range apps {
+		current, ok := installed[app.installPath(dir)]
+		if !ok || current.Version == "" || app.Repo == "" {
+			continue
+		}
+		notes, err := fetchNotes(ctx, policy, app.Repo, current.Version, app.Version)
+		if err != nil {
+			console.errorf("Failed to get the release notes of %s: %v", app.Repo, err)
+			continue
+		}
+		fmt.Fprintf(output, "\nChanges in %s from %s to %s:\n", app.displayName(), current.Version, app.Version)
+		if len(notes) == 0 {
+			fmt.Fprintln(output, "\n  No release notes were published.")
+		}
+		for _, note := range notes {
+			title := note.Version
+			if note.Name != "" && note.Name != note.Version {
+				title += " - " + note.Name
+			}
+			fmt.Fprintf(output, "\n## %s\n", title)
+			if body := strings.TrimSpace(strings.ReplaceAll(note.Body, "\r\n", "\n")); body != "" {
+				fmt.Fprintf(output, "\n%s\n", body)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+// releaseHistory serves the releases of o/tool over two pages, counting the
+// requests it answered with 304 Not Modified.
+func releaseHistory(t *testing.T) (retryPolicy, *int) {
+	var base string
+	notModified := new(int)
+	policy := testAPI(t, withETag(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("page") {
+		case "":
+			w.Header().Set("Link", fmt.Sprintf(`<%s/repos/o/tool/releases?per_page=100&page=2>; rel="next"`, base))
+			fmt.Fprint(w, `[
+				{"tag_name":"v1.4.0","body":"too new"},
+				{"tag_name":"v1.3.0","name":"Faster","body":"faster\r\nsmaller"},
+				{"tag_name":"v1.3.0-rc1","body":"candidate","prerelease":true},
+				{"tag_name":"v1.2.1","body":"draft","draft":true}
+			]`)
+		case "2":
+			w.Header().Set("Link", fmt.Sprintf(`<%s/repos/o/tool/releases?per_page=100&page=3>; rel="next"`, base))
+			fmt.Fprint(w, `[
+				{"tag_name":"v1.2.0","body":"fixes"},
+				{"tag_name":"v1.0.0","body":"installed"},
+				{"tag_name":"v0.9.0","body":"old"}
+			]`)
+		default:
+			t.Errorf("asked for page %s after reaching the installed version", r.URL.Query().Get("page"))
+			fmt.Fprint(w, `[]`)
+		}
+	}), notModified))
+	base = apiBase
+	return policy, notModified
+}
+
+func TestFetchNotes(t *testing.T) {
+	policy, _ := releaseHistory(t)
+	notes, err := fetchNotes(context.Background(), policy, "o/tool", "v1.0.0", "v1.3.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []releaseNote{{"v1.3.0", "Faster", "faster\r\nsmaller"}, {"v1.2.0", "", "fixes"}}
+	if fmt.Sprint(notes) != fmt.Sprint(want) {
+		t.Errorf("got %v, want %v", notes, want)
+	}
+}
+
+func TestFetchNotesCached(t *testing.T) {
+	testCache(t)
+	policy, notModified := releaseHistory(t)
+	for run := 1; run <= 2; run++ {
+		notes, err := fetchNotes(context.Background(), policy, "o/tool", "v1.0.0", "v1.3.0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(notes) != 2 || notes[1].Version != "v1.2.0" {
+			t.Errorf("run %d got %v, want the notes from both pages", run, notes)
+		}
+	}
+	if *notModified != 2 {
+		t.Errorf("%d page(s) came from the cache the second time, want both", *notModified)
+	}
+}
+
+func TestFetchNotesGitLab(t *testing.T) {
+	policy := testAPI(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.EscapedPath() != "/projects/g%2Ftool/releases" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, `[{"tag_name":"v2","description":"new things"},{"tag_name":"v1","description":"installed"}]`)
+	}))
+	oldGitLab := gitLabAPI
+	gitLabAPI = apiBase
+	t.Cleanup(func() { gitLabAPI = oldGitLab })
+
+	notes, err := fetchNotes(context.Background(), policy, "gitlab:g/tool", "v1", "v2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(notes) != 1 || notes[0].Version != "v2" || notes[0].Body != "new things" {
+		t.Errorf("got %v", notes)
+	}
+}
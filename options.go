@@ -0,0 +1,354 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	ScopeUser    = "user"
+	ScopeProject = "project"
+)
+
+type options struct {
+	GracePeriod bool
+	Scope       string
+
+	StallTimeout time.Duration
+	StallRetries int
+
+	TrustedAuthors trustedAuthors
+
+	ReportUnmatched bool
+
+	API      retryPolicy
+	Download retryPolicy
+
+	JSONLines string
+
+	LocalSource string
+
+	ChecksumAlgo string
+
+	InstallTimeout time.Duration
+
+	GraphQL bool
+
+	PrunePath bool
+
+	Color string
+
+	PinnedChecksums map[string]string
+
+	Profile        string
+	RepoFileFormat string
+
+	RetryFailed bool
+	MaxRateWait time.Duration
+
+	ListAssets string
+
+	ResolveOnly  bool
+	FromResolved bool
+	ResolvedTTL  time.Duration
+
+	Dequarantine bool
+
+	DryRun bool
+
+	RepoList   string
+	InstallDir string
+
+	Jobs int
+
+	Uninstall bool
+	Update    bool
+
+	OS   string
+	Arch string
+
+	List bool
+	JSON bool
+
+	Yes bool
+
+	Timeout time.Duration
+
+	NoCache  bool
+	CacheTTL time.Duration
+
+	Quiet   bool
+	Verbose bool
+
+	Config    string
+	TokenEnv  string
+	TokenFile string
+
+	APIBase string
+
+	UniversalAsset string
+
+	PrintVersion bool
+	SelfUpdate   bool
+
+	ConfirmAbove int64
+
+	PrefixName string
+
+	Force bool
+
+	VerifyKey string
+	Verifier  Verifier
+
+	NoBanner bool
+
+	Command  string
+	Names    []string
+	Outdated bool
+	Sync     bool
+
+	All      bool
+	KeepPath bool
+
+	Proxy string
+	Libc  string
+
+	GitLabAPI string
+
+	ShowNotes bool
+
+	RequireChecksum bool
+
+	IgnorePins bool
+
+	LockFile string
+	FromLock string
+
+	sources map[string]string
+}
+
+func parseFlags() (options, error) {
+	opts := options{TrustedAuthors: trustedAuthors{}}
+	flag.BoolVar(&opts.GracePeriod, "grace-period", false, "install binaries but defer PATH changes until 'donut-utils apply-path' is run")
+	flag.StringVar(&opts.Scope, "scope", ScopeUser, "install scope: 'user' installs into ~/.donut-utils, 'project' into ./.donut-utils")
+	flag.DurationVar(&opts.StallTimeout, "stall-timeout", 60*time.Second, "cancel a download that makes no progress for this long, 0 disables the watchdog")
+	flag.IntVar(&opts.StallRetries, "stall-retries", 1, "how many times to retry a download cancelled by the watchdog")
+	flag.Var(opts.TrustedAuthors, "trusted-author", "only install releases of owner/repo published by login, given as owner/repo=login (repeatable)")
+	flag.BoolVar(&opts.ReportUnmatched, "report-unmatched", false, "list repos whose release has no asset for this platform, with the assets it does have")
+	flag.IntVar(&opts.API.Retries, "api-retries", 3, "how many times to retry a failed GitHub API request")
+	flag.DurationVar(&opts.API.Timeout, "api-timeout", 15*time.Second, "timeout for each GitHub API request")
+	flag.IntVar(&opts.Download.Retries, "download-retries", 2, "how many times to retry a download that fails to start")
+	flag.DurationVar(&opts.Download.Timeout, "download-timeout", 10*time.Minute, "timeout for each download, 0 means no limit")
+	flag.StringVar(&opts.JSONLines, "as-json-lines", "", "stream progress events as JSON lines to stdout, stderr or a file path")
+	flag.StringVar(&opts.LocalSource, "local-source", "", "install platform binaries from this local directory instead of GitHub releases")
+	flag.StringVar(&opts.ChecksumAlgo, "checksum-algo", AlgoAuto, "checksum algorithm used by releases: auto, sha256, sha512 or blake2b")
+	flag.DurationVar(&opts.InstallTimeout, "install-timeout", 0, "cancel the whole run if it takes longer than this, 0 means no limit")
+	flag.BoolVar(&opts.GraphQL, "graphql", true, "when GITHUB_TOKEN is set, look up releases in batches over the GraphQL API")
+	flag.BoolVar(&opts.PrunePath, "prune-path", false, "remove stale donut-utils PATH entries from your shell profiles and exit")
+	flag.StringVar(&opts.Color, "color", ColorAuto, "color status messages: auto, always or never (NO_COLOR is honored in auto)")
+	flag.StringVar(&opts.Profile, "profile", "", "only install entries of a YAML or JSON repo list with this profile, plus those without one")
+	flag.StringVar(&opts.RepoFileFormat, "repo-file-format", FormatAuto, "format of the repo list: auto (from the file extension), text, yaml or json")
+	pinnedPath := flag.String("pinned-checksums", defaultPinnedChecksumsPath(), "file of \"<digest>  owner/repo@version\" lines to verify downloads against")
+	flag.BoolVar(&opts.RetryFailed, "retry-failed", false, "only retry the apps that failed in the last run")
+	flag.DurationVar(&opts.MaxRateWait, "max-rate-wait", time.Minute, "wait for the GitHub rate limit to reset when it is this close, otherwise stop looking up repos")
+	flag.StringVar(&opts.ListAssets, "list-assets", "", "print the release assets of owner/repo and which one would be installed, then exit")
+	flag.BoolVar(&opts.ResolveOnly, "resolve-only", false, "look up and save the available apps without downloading anything")
+	flag.BoolVar(&opts.FromResolved, "from-resolved", false, "download the apps saved by --resolve-only instead of looking them up again")
+	flag.DurationVar(&opts.ResolvedTTL, "resolved-ttl", 24*time.Hour, "how long apps saved by --resolve-only stay usable, 0 means forever")
+	flag.BoolVar(&opts.Dequarantine, "dequarantine", false, "on macOS, remove the quarantine attribute from installed binaries so Gatekeeper doesn't block them")
+	flag.BoolVar(&opts.DryRun, "dry-run", false, "show what would be installed and where without downloading anything or changing PATH")
+	flag.StringVar(&opts.RepoList, "repolist", "", "read the repo list from this file or https URL instead of looking for repolist.txt")
+	flag.StringVar(&opts.InstallDir, "install-dir", "", "install into this directory instead of the scope's default, ~ is expanded")
+	flag.IntVar(&opts.Jobs, "jobs", 4, "how many apps to download at once")
+	flag.BoolVar(&opts.Uninstall, "uninstall", false, "remove the installed binaries and the PATH line added for them")
+	flag.BoolVar(&opts.Update, "update", false, "only download apps with a newer release than the installed one, without asking")
+	flag.StringVar(&opts.OS, "os", "", "pick assets for this OS instead of the current one, skips PATH changes")
+	flag.StringVar(&opts.Arch, "arch", "", "pick assets for this architecture, like arm64 or armv6, instead of the current one, skips PATH changes")
+	flag.BoolVar(&opts.List, "list", false, "print a table of the apps available for your platform and exit")
+	flag.BoolVar(&opts.JSON, "json", false, "print the available apps as a JSON array on stdout and exit")
+	flag.BoolVar(&opts.Yes, "yes", false, "don't wait before starting and download every available app without asking")
+	flag.BoolVar(&opts.Yes, "y", false, "shorthand for --yes")
+	flag.DurationVar(&opts.Timeout, "timeout", 30*time.Second, "how long any request may take to connect and start responding")
+	flag.BoolVar(&opts.NoCache, "no-cache", false, "always ask the GitHub API instead of reusing cached responses")
+	flag.DurationVar(&opts.CacheTTL, "cache-ttl", 24*time.Hour, "how long cached GitHub API responses are kept")
+	flag.BoolVar(&opts.Quiet, "quiet", false, "only print errors and the final summary")
+	flag.BoolVar(&opts.Verbose, "verbose", false, "also print each request, how assets were picked and checksum results")
+	flag.StringVar(&opts.Config, "config", defaultConfigPath(), "read defaults for any of these flags from this JSON or YAML file, keyed by flag name")
+	flag.StringVar(&opts.TokenEnv, "token-env", "", "read the GitHub token from this environment variable before the usual ones")
+	flag.StringVar(&opts.TokenFile, "token-file", "", "read the GitHub token from this file when it isn't in the environment")
+	flag.StringVar(&opts.APIBase, "api-base", DefaultAPI, "GitHub API root, such as https://github.example.com/api/v3 for GitHub Enterprise")
+	flag.StringVar(&opts.UniversalAsset, "universal-asset", "", "asset pattern, like *.jar, to install when a release has nothing for this platform")
+	flag.BoolVar(&opts.PrintVersion, "version", false, "print the version of donut-utils and exit")
+	flag.BoolVar(&opts.SelfUpdate, "self-update", false, "replace donut-utils with its latest release when that's newer, then exit")
+	flag.Int64Var(&opts.ConfirmAbove, "confirm-above", 500, "ask again before downloading more than this many MB in total, 0 never asks")
+	flag.StringVar(&opts.PrefixName, "prefix-name", "", "prepend this to installed file names, {owner} and {repo} are replaced by the app's repo")
+	flag.BoolVar(&opts.Force, "force", false, "overwrite files in the install directory that donut-utils didn't install for the same app")
+	flag.StringVar(&opts.VerifyKey, "verify-key", "", "minisign or GPG public key file to check the signatures releases publish next to their assets")
+	flag.BoolVar(&opts.NoBanner, "no-banner", false, "don't print the banner, it's also left out when stdout isn't a terminal")
+	flag.BoolVar(&opts.RequireChecksum, "require-checksum", false, "refuse to install downloads with neither a release nor a pinned checksum, instead of warning")
+	flag.BoolVar(&opts.All, "all", false, "with remove, remove every app donut-utils installed, the same as --uninstall")
+	flag.BoolVar(&opts.KeepPath, "keep-path", false, "when uninstalling everything, leave the PATH line in your shell profiles")
+	flag.StringVar(&opts.Proxy, "proxy", "", "send every request through this HTTP proxy instead of the one in HTTPS_PROXY or HTTP_PROXY")
+	flag.StringVar(&opts.Libc, "libc", LibcAuto, "pick Linux assets built for musl or glibc, auto detects the one this machine uses")
+	flag.StringVar(&opts.GitLabAPI, "gitlab-api", DefaultGitLabAPI, "GitLab API root for gitlab: repos, such as https://gitlab.example.com/api/v4 for a self-hosted GitLab")
+	flag.BoolVar(&opts.ShowNotes, "show-notes", false, "with update, print the release notes of every version between the installed one and the new one before updating")
+	flag.BoolVar(&opts.IgnorePins, "ignore-pins", false, "with update, update repos pinned to a release in the repo list to their latest release")
+	flag.StringVar(&opts.LockFile, "lockfile", LockFile, "file freeze writes the installed apps to")
+	flag.StringVar(&opts.FromLock, "from-lock", "", "install the apps of this lockfile written by freeze, at the versions and with the binaries it records, instead of the repo list")
+	flag.Usage = func() {
+		out := flag.CommandLine.Output()
+		fmt.Fprint(out, usageHelp)
+		flag.PrintDefaults()
+		fmt.Fprint(out, exitCodesHelp)
+	}
+	flag.Parse()
+
+	command, names, err := parseCommand(flag.CommandLine)
+	if err != nil {
+		return opts, err
+	}
+	opts.Command, opts.Names = command, names
+	switch command {
+	case CmdList:
+		opts.List = true
+	case CmdUpdate:
+		opts.Update = true
+	case CmdOutdated:
+		opts.Outdated = true
+	case CmdSync:
+		opts.Sync = true
+	case CmdRemove:
+		if opts.All && len(names) > 0 {
+			return opts, fmt.Errorf("name the apps to remove or use --all, not both")
+		}
+		if !opts.All && len(names) == 0 && opts.Profile == "" {
+			return opts, fmt.Errorf("name the apps to remove, like 'donut-utils remove checksum', or use --profile or --all")
+		}
+		opts.Uninstall = opts.All
+	}
+
+	sources, err := applyEnv(flag.CommandLine)
+	if err != nil {
+		return opts, err
+	}
+	if err := applyConfig(flag.CommandLine, opts.Config, sources); err != nil {
+		return opts, err
+	}
+	opts.sources = sources
+	tokenEnv = opts.TokenEnv
+	if opts.TokenFile != "" {
+		path, err := expandPath(opts.TokenFile)
+		if err != nil {
+			return opts, err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return opts, fmt.Errorf("failed to read --token-file: %w", err)
+		}
+		fileToken = strings.TrimSpace(string(data))
+	}
+
+	if err := setupColor(opts.Color, opts.eventsOnStdout()); err != nil {
+		return opts, err
+	}
+	if err := setupVerbosity(opts.Quiet, opts.Verbose); err != nil {
+		return opts, err
+	}
+	if _, ok := checksumAlgos[opts.ChecksumAlgo]; !ok && opts.ChecksumAlgo != AlgoAuto {
+		return opts, fmt.Errorf("invalid --checksum-algo %q, expected auto, sha256, sha512 or blake2b", opts.ChecksumAlgo)
+	}
+	if opts.Jobs < 1 {
+		return opts, fmt.Errorf("invalid --jobs %d, expected at least 1", opts.Jobs)
+	}
+	if opts.Scope != ScopeUser && opts.Scope != ScopeProject {
+		return opts, fmt.Errorf("invalid --scope %q, expected %s or %s", opts.Scope, ScopeUser, ScopeProject)
+	}
+
+	if opts.FromLock != "" && (opts.FromResolved || opts.LocalSource != "") {
+		return opts, fmt.Errorf("--from-lock can't be combined with --from-resolved or --local-source")
+	}
+
+	if opts.RepoList == StdinRepoList && !opts.Yes && !opts.Update && !opts.readOnly() && !opts.ResolveOnly {
+		return opts, fmt.Errorf("--repolist - reads the repo list from stdin, which leaves nothing to answer prompts with, add --yes")
+	}
+
+	if opts.Timeout <= 0 {
+		return opts, fmt.Errorf("invalid --timeout %s, expected a positive duration", opts.Timeout)
+	}
+	var proxy *url.URL
+	if opts.Proxy != "" {
+		proxy, err = url.Parse(opts.Proxy)
+		if err != nil || proxy.Host == "" {
+			return opts, fmt.Errorf("invalid --proxy %q, expected a URL like http://proxy.example.com:3128", opts.Proxy)
+		}
+	}
+	transport = newTransport(opts.Timeout, proxy)
+
+	base, err := url.Parse(opts.APIBase)
+	if err != nil || (base.Scheme != "http" && base.Scheme != "https") || base.Host == "" {
+		return opts, fmt.Errorf("invalid --api-base %q, expected an http or https URL", opts.APIBase)
+	}
+	apiBase = strings.TrimSuffix(opts.APIBase, "/")
+
+	base, err = url.Parse(opts.GitLabAPI)
+	if err != nil || (base.Scheme != "http" && base.Scheme != "https") || base.Host == "" {
+		return opts, fmt.Errorf("invalid --gitlab-api %q, expected an http or https URL", opts.GitLabAPI)
+	}
+	gitLabAPI = strings.TrimSuffix(opts.GitLabAPI, "/")
+
+	if opts.OS != "" {
+		targetOS = opts.OS
+	}
+	if opts.Arch != "" {
+		targetArch, targetARM = parseARMArch(opts.Arch)
+	}
+	switch opts.Libc {
+	case LibcAuto:
+		if opts.OS != "" || opts.Arch != "" {
+			targetLibc = ""
+		}
+	case LibcMusl, LibcGlibc:
+		targetLibc = opts.Libc
+	default:
+		return opts, fmt.Errorf("invalid --libc %q, expected auto, musl or glibc", opts.Libc)
+	}
+
+	pinned, err := loadPinnedChecksums(*pinnedPath)
+	if err != nil {
+		return opts, fmt.Errorf("failed to read pinned checksums: %w", err)
+	}
+	opts.PinnedChecksums = pinned
+
+	if opts.VerifyKey != "" {
+		opts.Verifier, err = loadVerifier(opts.VerifyKey)
+		if err != nil {
+			return opts, fmt.Errorf("failed to read --verify-key: %w", err)
+		}
+	}
+	return opts, nil
+}
+
+// eventsOnStdout reports whether --as-json-lines streams its events to
+// stdout, which then carries nothing else.
+func (o options) eventsOnStdout() bool {
+	return o.JSONLines == "stdout" || o.JSONLines == "-"
+}
+
+// readOnly reports whether the run only looks things up, so nothing should
+// be written to the install directory.
+func (o options) readOnly() bool {
+	return o.DryRun || o.List || o.JSON || o.Outdated
+}
+
+func defaultPinnedChecksumsPath() string {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(configDir, "donut-utils", "checksums.txt")
+}
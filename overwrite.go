@@ -0,0 +1,50 @@
+package main
+
+import (
+	"os"
+)
+
+// foreignFile reports whether target holds a file donut-utils didn't
+// install for app's repo, like a binary put there by hand, another repo's
+// binary of the same name or one replaced since it was installed. Updating
+// an app's own binary is fine.
+func foreignFile(installed manifest, app appInfo, target string) bool {
+	if _, err := os.Stat(target); err != nil {
+		return false
+	}
+	current, ok := installed[target]
+	if !ok || current.Repo != app.Repo {
+		return true
+	}
+	if current.SHA256 == "" {
+		return false
+	}
+	digest, err := fileDigest(target, AlgoSHA256)
+	return err == nil && digest != current.SHA256
+}
+
+// keepForeignFiles drops the apps that would overwrite a foreign file,
+// unless --force is set or the user agrees to overwrite it. Without a
+// terminal to ask on, or with --yes, they're skipped.
+func keepForeignFiles(opts options, dir string, apps []appInfo, summary *runSummary) []appInfo {
+	if opts.Force {
+		return apps
+	}
+	installed, err := loadManifest(dir)
+	if err != nil {
+		console.errorf("Failed to read install manifest: %v", err)
+		installed = make(manifest)
+	}
+
+	interactive := !opts.Yes && isTerminal(os.Stdin)
+	var keep []appInfo
+	for _, app := range apps {
+		target := app.installPath(dir)
+		if !foreignFile(installed, app, target) || (interactive && confirmOverwrite(app, target)) {
+			keep = append(keep, app)
+			continue
+		}
+		summary.skip(app.displayName(), target+" already exists and isn't the file donut-utils installed for this app, use --force to overwrite it")
+	}
+	return keep
+}
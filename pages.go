@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// GitHub returns 30 items a page unless asked for up to 100. A release
+// embedding a full page of assets may have more on later pages.
+const (
+	DefaultPageSize = 30
+	MaxPageSize     = 100
+)
+
+// fetchAssets lists every asset of a release from its assets endpoint,
+// following the pages GitHub splits the list into.
+func fetchAssets(ctx context.Context, policy retryPolicy, assetsURL string) ([]releaseAsset, error) {
+	var assets []releaseAsset
+	next := fmt.Sprintf("%s?per_page=%d", assetsURL, MaxPageSize)
+	for next != "" {
+		resp, err := policy.githubGet(ctx, next)
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode != 200 {
+			resp.Body.Close()
+			return nil, fmt.Errorf("listing release assets returned response code %d", resp.StatusCode)
+		}
+
+		var page []releaseAsset
+		err = json.NewDecoder(resp.Body).Decode(&page)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		assets = append(assets, page...)
+		next = nextPage(resp)
+	}
+	return assets, nil
+}
+
+// nextPage returns the rel="next" URL of a response's Link header, or ""
+// on the last page.
+func nextPage(resp *http.Response) string {
+	for _, link := range strings.Split(resp.Header.Get("Link"), ",") {
+		target, params, ok := strings.Cut(link, ";")
+		if ok && strings.Contains(params, `rel="next"`) {
+			return strings.Trim(strings.TrimSpace(target), "<>")
+		}
+	}
+	return ""
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+// pagedAssets serves a release whose embedded asset list is a full first
+// page, with the asset for linux/amd64 only on page two of its assets
+// endpoint. It counts the requests it answered with 304 Not Modified.
+func pagedAssets(t *testing.T) (retryPolicy, *int) {
+	var first []releaseAsset
+	for i := 0; i < DefaultPageSize; i++ {
+		first = append(first, releaseAsset{Name: fmt.Sprintf("tool-plan9-mips%d", i)})
+	}
+	second := []releaseAsset{{Name: "tool-linux-amd64"}, {Name: "tool-linux-amd64.sha256"}}
+
+	var base string
+	notModified := new(int)
+	policy := testAPI(t, withETag(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/repos/o/tool/releases/latest":
+			json.NewEncoder(w).Encode(map[string]any{
+				"tag_name":   "v1",
+				"assets":     first,
+				"assets_url": base + "/repos/o/tool/releases/1/assets",
+			})
+		case r.URL.Path == "/repos/o/tool/releases/1/assets" && r.URL.Query().Get("page") == "":
+			if r.URL.Query().Get("per_page") != fmt.Sprint(MaxPageSize) {
+				t.Errorf("first page asked for per_page=%s", r.URL.Query().Get("per_page"))
+			}
+			w.Header().Set("Link", fmt.Sprintf(`<%s/repos/o/tool/releases/1/assets?per_page=%d&page=2>; rel="next", <%[1]s/repos/o/tool/releases/1/assets?per_page=%[2]d&page=2>; rel="last"`, base, MaxPageSize))
+			json.NewEncoder(w).Encode(first)
+		case r.URL.Path == "/repos/o/tool/releases/1/assets" && r.URL.Query().Get("page") == "2":
+			w.Header().Set("Link", fmt.Sprintf(`<%s/repos/o/tool/releases/1/assets?per_page=%d&page=1>; rel="prev"`, base, MaxPageSize))
+			json.NewEncoder(w).Encode(second)
+		default:
+			http.NotFound(w, r)
+		}
+	}), notModified))
+	base = apiBase
+	return policy, notModified
+}
+
+func TestFetchAssetsFollowsNextPage(t *testing.T) {
+	policy, _ := pagedAssets(t)
+	assets, err := fetchAssets(context.Background(), policy, apiBase+"/repos/o/tool/releases/1/assets")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(assets) != DefaultPageSize+2 {
+		t.Fatalf("got %d assets, want %d", len(assets), DefaultPageSize+2)
+	}
+	if last := assets[len(assets)-1].Name; last != "tool-linux-amd64.sha256" {
+		t.Errorf("last asset is %s, want the end of page two", last)
+	}
+}
+
+func TestFetchAssetsCached(t *testing.T) {
+	testCache(t)
+	policy, notModified := pagedAssets(t)
+	url := apiBase + "/repos/o/tool/releases/1/assets"
+	for run := 1; run <= 2; run++ {
+		assets, err := fetchAssets(context.Background(), policy, url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(assets) != DefaultPageSize+2 {
+			t.Errorf("run %d got %d assets, want %d", run, len(assets), DefaultPageSize+2)
+		}
+	}
+	if *notModified != 2 {
+		t.Errorf("%d page(s) came from the cache the second time, want both", *notModified)
+	}
+}
+
+func TestFetchReleaseConsidersPageTwo(t *testing.T) {
+	testPlatform(t, "linux", "amd64")
+	policy, _ := pagedAssets(t)
+	release, err := fetchRelease(context.Background(), policy, repoEntry{Repo: "o/tool"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	asset, ok := selectAsset(release.Assets, "linux", "amd64")
+	if !ok || asset.Name != "tool-linux-amd64" {
+		t.Errorf("got %q, %v, want the asset from page two", asset.Name, ok)
+	}
+}
+
+func TestNextPage(t *testing.T) {
+	tests := []struct {
+		link string
+		want string
+	}{
+		{"", ""},
+		{`<https://api.github.com/x?page=2>; rel="next", <https://api.github.com/x?page=5>; rel="last"`, "https://api.github.com/x?page=2"},
+		{`<https://api.github.com/x?page=1>; rel="prev", <https://api.github.com/x?page=3>; rel="next"`, "https://api.github.com/x?page=3"},
+		{`<https://api.github.com/x?page=1>; rel="first", <https://api.github.com/x?page=4>; rel="prev"`, ""},
+	}
+	for _, tt := range tests {
+		resp := &http.Response{Header: http.Header{"Link": {tt.link}}}
+		if got := nextPage(resp); got != tt.want {
+			t.Errorf("nextPage(%q) = %q, want %q", tt.link, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"runtime"
+	"strings"
+)
+
+// targetOS and targetArch are the platform assets are picked for, this
+// machine's unless --os or --arch stage them for another one.
+var (
+	targetOS   = runtime.GOOS
+	targetArch = runtime.GOARCH
+)
+
+// osAliases and archAliases hold the other names releases commonly use for
+// Go's GOOS and GOARCH values.
+var osAliases = map[string][]string{
+	"darwin":  {"darwin", "macos", "osx"},
+	"windows": {"windows", "win64", "win32"},
+}
+
+var archAliases = map[string][]string{
+	"amd64": {"amd64", "x86_64", "x86-64", "x64"},
+	"arm64": {"arm64", "aarch64", "armv8"},
+	"386":   {"386", "i386", "i686", "x86"},
+	"arm":   {"arm", "armv7", "armv6", "armhf"},
+}
+
+func platformAliases(aliases map[string][]string, name string) []string {
+	if names, ok := aliases[name]; ok {
+		return names
+	}
+	return []string{name}
+}
+
+// windowsArchs are the architectures win64 and win32 stand for in assets
+// that name no architecture of their own, like tool-win64.exe.
+var windowsArchs = map[string]string{"win64": "amd64", "win32": "386"}
+
+func matchesPlatform(assetName, goos, goarch string) bool {
+	name := strings.ToLower(assetName)
+	if !containsAlias(name, osAliases, goos) {
+		return false
+	}
+	if containsAlias(name, archAliases, goarch) {
+		return true
+	}
+	for word, arch := range windowsArchs {
+		if arch == goarch && strings.Contains(name, word) && !namesOtherArch(name, goarch) {
+			return true
+		}
+	}
+	return false
+}
+
+func namesOtherArch(name, goarch string) bool {
+	for arch := range archAliases {
+		if arch != goarch && containsAlias(name, archAliases, arch) {
+			return true
+		}
+	}
+	return false
+}
+
+// containsAlias reports whether name mentions target under any of its
+// aliases. Other platforms' names that merely contain one of those aliases,
+// like x86_64 for x86 or arm64 for arm, are masked out first.
+func containsAlias(name string, aliases map[string][]string, target string) bool {
+	own := platformAliases(aliases, target)
+	for other, names := range aliases {
+		if other == target {
+			continue
+		}
+		for _, alias := range names {
+			for _, mine := range own {
+				if alias != mine && strings.Contains(alias, mine) {
+					name = strings.ReplaceAll(name, alias, " ")
+				}
+			}
+		}
+	}
+
+	for _, alias := range own {
+		if strings.Contains(name, alias) {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	packageExts = []string{".deb", ".rpm", ".apk", ".msi", ".pkg", ".dmg"}
+	sidecarExts = []string{".sig", ".minisig", ".asc", ".pem", ".txt", ".json", ".jsonl", ".sbom", ".md"}
+)
+
+// assetScore ranks how likely an asset is to be the installable binary: a
+// bare executable beats an archive, which beats an OS package. Signatures,
+// notes and other sidecar files score below zero and are never picked.
+func assetScore(name string) int {
+	lower := strings.ToLower(name)
+	if isChecksumAsset(lower) || hasAnySuffix(lower, sidecarExts) {
+		return -1
+	}
+	if archiveExt(lower) != "" {
+		return 2
+	}
+	if hasAnySuffix(lower, packageExts) {
+		return 0
+	}
+	return 3
+}
+
+func hasAnySuffix(name string, suffixes []string) bool {
+	for _, suffix := range suffixes {
+		if strings.HasSuffix(name, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// bestAsset picks the highest scoring asset, the earliest one on a tie.
+func bestAsset(assets []releaseAsset) (releaseAsset, bool) {
+	var best releaseAsset
+	bestScore := -1
+	for _, asset := range assets {
+		score := assetScore(asset.Name)
+		console.debugf("  %s scores %d", asset.Name, score)
+		if score > bestScore {
+			best, bestScore = asset, score
+		}
+	}
+	return best, bestScore >= 0
+}
+
+func filterAssets(assets []releaseAsset, match func(name string) bool) []releaseAsset {
+	var matched []releaseAsset
+	for _, asset := range assets {
+		if match(asset.Name) {
+			matched = append(matched, asset)
+		}
+	}
+	return matched
+}
+
+// universalAsset picks a platform-agnostic asset, like a script or a .jar,
+// for a release with nothing built for the platform. With a pattern it's the
+// best asset matching it. Without one the release must have a single
+// installable asset that doesn't name a platform, so releases built for
+// other platforms are still skipped.
+func universalAsset(assets []releaseAsset, pattern string) (releaseAsset, bool) {
+	if pattern != "" {
+		return bestAsset(filterAssets(assets, func(name string) bool { return matchesAssetPattern(pattern, name) }))
+	}
+	installable := filterAssets(assets, func(name string) bool { return assetScore(name) >= 0 })
+	if len(installable) != 1 || namesPlatform(installable[0].Name) {
+		return releaseAsset{}, false
+	}
+	return installable[0], true
+}
+
+func namesPlatform(name string) bool {
+	words := strings.FieldsFunc(strings.ToLower(name), func(r rune) bool { return r == '-' || r == '_' || r == '.' })
+	for _, word := range words {
+		if platformWords[word] {
+			return true
+		}
+	}
+	return false
+}
+
+// universalArchs are the names of macOS binaries built for every
+// architecture at once.
+var universalArchs = []string{"universal", "all"}
+
+// selectAsset picks the asset to install for a platform out of a release.
+// On Linux the assets built for the C library in use are preferred, and on
+// ARM those for the board's ARM version. On macOS a universal binary is the
+// fallback when no asset names the architecture.
+func selectAsset(assets []releaseAsset, goos, goarch string) (releaseAsset, bool) {
+	matched := filterAssets(assets, func(name string) bool { return matchesPlatform(name, goos, goarch) })
+	if goos == "linux" && targetLibc != "" {
+		matched = preferLibc(matched, targetLibc)
+	}
+	if goarch == "arm" && targetARM != "" {
+		return bestARMAsset(matched, targetARM)
+	}
+	if asset, ok := bestAsset(matched); ok {
+		return asset, true
+	}
+	if goos != "darwin" {
+		return releaseAsset{}, false
+	}
+	return bestAsset(filterAssets(assets, func(name string) bool {
+		words := strings.FieldsFunc(strings.ToLower(name), func(r rune) bool { return r == '-' || r == '_' || r == '.' })
+		for _, word := range words {
+			for _, universal := range universalArchs {
+				if word == universal {
+					return containsAlias(strings.ToLower(name), osAliases, goos)
+				}
+			}
+		}
+		return false
+	}))
+}
@@ -0,0 +1,215 @@
+package main
+
+import "testing"
+
+func TestMatchesPlatform(t *testing.T) {
+	tests := []struct {
+		name   string
+		goos   string
+		goarch string
+		want   bool
+	}{
+		{"tool-linux-amd64", "linux", "amd64", true},
+		{"tool_Linux_x86_64.tar.gz", "linux", "amd64", true},
+		{"tool-linux-x86-64", "linux", "amd64", true},
+		{"tool-linux-x64", "linux", "amd64", true},
+		{"tool-linux-amd64", "linux", "arm64", false},
+		{"tool-linux-amd64", "darwin", "amd64", false},
+
+		// x86 is 386, but x86_64 and x86-64 are amd64.
+		{"tool-linux-x86", "linux", "386", true},
+		{"tool-linux-i386", "linux", "386", true},
+		{"tool-linux-i686", "linux", "386", true},
+		{"tool-linux-x86_64", "linux", "386", false},
+		{"tool-linux-x86-64", "linux", "386", false},
+		{"tool-linux-x86", "linux", "amd64", false},
+
+		// arm is 32-bit, arm64, aarch64 and armv8 are 64-bit.
+		{"tool-linux-arm64", "linux", "arm64", true},
+		{"tool-linux-aarch64", "linux", "arm64", true},
+		{"tool-linux-armv8", "linux", "arm64", true},
+		{"tool-linux-arm64", "linux", "arm", false},
+		{"tool-linux-aarch64", "linux", "arm", false},
+		{"tool-linux-armv8", "linux", "arm", false},
+		{"tool-linux-arm", "linux", "arm", true},
+		{"tool-linux-armv6", "linux", "arm", true},
+		{"tool-linux-armv7", "linux", "arm", true},
+		{"tool-linux-armhf", "linux", "arm", true},
+		{"tool-linux-armv7", "linux", "arm64", false},
+
+		{"tool-darwin-arm64", "darwin", "arm64", true},
+		{"tool-macos-arm64", "darwin", "arm64", true},
+		{"tool-osx-x86_64", "darwin", "amd64", true},
+		{"tool_macOS_amd64.zip", "darwin", "amd64", true},
+		{"tool-macos-arm64", "linux", "arm64", false},
+
+		{"tool-windows-amd64.exe", "windows", "amd64", true},
+		{"tool-win64-x86_64.zip", "windows", "amd64", true},
+		{"tool-win64.exe", "windows", "amd64", true},
+		{"tool-win64.exe", "windows", "386", false},
+		{"tool-win32.exe", "windows", "386", true},
+		{"tool-win32.exe", "windows", "amd64", false},
+		{"tool-win64-arm64.exe", "windows", "amd64", false},
+		{"tool-win64-arm64.exe", "windows", "arm64", true},
+		{"tool-darwin-amd64", "windows", "amd64", false},
+	}
+	for _, tt := range tests {
+		if got := matchesPlatform(tt.name, tt.goos, tt.goarch); got != tt.want {
+			t.Errorf("matchesPlatform(%q, %q, %q) = %v, want %v", tt.name, tt.goos, tt.goarch, got, tt.want)
+		}
+	}
+}
+
+func assetList(names ...string) []releaseAsset {
+	var assets []releaseAsset
+	for _, name := range names {
+		assets = append(assets, releaseAsset{Name: name})
+	}
+	return assets
+}
+
+func TestSelectAsset(t *testing.T) {
+	testPlatform(t, "linux", "amd64")
+	tests := []struct {
+		desc   string
+		assets []releaseAsset
+		goos   string
+		goarch string
+		want   string
+	}{
+		{
+			desc:   "bare binary beats its checksum, signature, archive and package",
+			assets: assetList("tool-linux-amd64.sha256", "tool-linux-amd64.sig", "tool-linux-amd64.tar.gz", "tool_linux_amd64.deb", "tool-linux-amd64", "tool-darwin-amd64"),
+			goos:   "linux", goarch: "amd64",
+			want: "tool-linux-amd64",
+		},
+		{
+			desc:   "archive beats package and sidecars",
+			assets: assetList("tool-linux-amd64.tar.gz.sha256", "tool-linux-amd64.tar.gz.minisig", "tool-linux-amd64.deb", "tool-linux-amd64.tar.gz"),
+			goos:   "linux", goarch: "amd64",
+			want: "tool-linux-amd64.tar.gz",
+		},
+		{
+			desc:   "package beats sidecars",
+			assets: assetList("tool_1.0_linux_amd64.rpm.asc", "tool_1.0_linux_amd64.rpm"),
+			goos:   "linux", goarch: "amd64",
+			want: "tool_1.0_linux_amd64.rpm",
+		},
+		{
+			desc:   "the earliest wins a tie",
+			assets: assetList("tool-linux-x86_64.zip", "tool-linux-amd64.tar.gz"),
+			goos:   "linux", goarch: "amd64",
+			want: "tool-linux-x86_64.zip",
+		},
+		{
+			desc:   "sidecars alone are never picked",
+			assets: assetList("tool-linux-amd64.sha256", "tool-linux-amd64.sig", "checksums_linux_amd64.txt", "tool-linux-amd64.sbom.json"),
+			goos:   "linux", goarch: "amd64",
+		},
+		{
+			desc:   "nothing for the platform",
+			assets: assetList("tool-darwin-arm64", "tool-windows-amd64.exe"),
+			goos:   "linux", goarch: "amd64",
+		},
+		{
+			desc:   "universal macOS binary when no asset names the architecture",
+			assets: assetList("tool-darwin-universal.tar.gz.sha256", "tool-darwin-universal.tar.gz", "tool-linux-arm64"),
+			goos:   "darwin", goarch: "arm64",
+			want: "tool-darwin-universal.tar.gz",
+		},
+		{
+			desc:   "architecture-specific macOS binary beats the universal one",
+			assets: assetList("tool_macos_all.zip", "tool-macos-arm64.zip"),
+			goos:   "darwin", goarch: "arm64",
+			want: "tool-macos-arm64.zip",
+		},
+		{
+			desc:   "universal binaries are only a macOS fallback",
+			assets: assetList("tool-linux-universal"),
+			goos:   "linux", goarch: "amd64",
+		},
+	}
+	for _, tt := range tests {
+		asset, ok := selectAsset(tt.assets, tt.goos, tt.goarch)
+		if ok != (tt.want != "") || asset.Name != tt.want {
+			t.Errorf("%s: got %q, %v, want %q", tt.desc, asset.Name, ok, tt.want)
+		}
+	}
+}
+
+func TestSelectAssetLibc(t *testing.T) {
+	tests := []struct {
+		libc   string
+		assets []releaseAsset
+		want   string
+	}{
+		{LibcMusl, assetList("tool-x86_64-unknown-linux-gnu.tar.gz", "tool-x86_64-unknown-linux-musl.tar.gz", "tool-linux-amd64-static"), "tool-x86_64-unknown-linux-musl.tar.gz"},
+		{LibcMusl, assetList("tool-x86_64-unknown-linux-gnu", "tool-linux-amd64-static.tar.gz"), "tool-linux-amd64-static.tar.gz"},
+		{LibcMusl, assetList("tool-x86_64-unknown-linux-gnu", "tool-linux-amd64.tar.gz"), "tool-linux-amd64.tar.gz"},
+		{LibcMusl, assetList("tool-x86_64-unknown-linux-gnu", "tool-x86_64-unknown-linux-musl.sha256"), ""},
+		{LibcGlibc, assetList("tool-x86_64-unknown-linux-musl", "tool-x86_64-unknown-linux-gnu.tar.gz"), "tool-x86_64-unknown-linux-gnu.tar.gz"},
+		{LibcGlibc, assetList("tool-x86_64-unknown-linux-musl", "tool-linux-amd64"), "tool-x86_64-unknown-linux-musl"},
+		{"", assetList("tool-x86_64-unknown-linux-musl.tar.gz", "tool-x86_64-unknown-linux-gnu"), "tool-x86_64-unknown-linux-gnu"},
+	}
+	for _, tt := range tests {
+		testPlatform(t, "linux", "amd64")
+		targetLibc = tt.libc
+		asset, ok := selectAsset(tt.assets, "linux", "amd64")
+		if ok != (tt.want != "") || asset.Name != tt.want {
+			t.Errorf("libc %q, %v: got %q, %v, want %q", tt.libc, tt.assets, asset.Name, ok, tt.want)
+		}
+	}
+
+	// The C library only matters on Linux.
+	testPlatform(t, "darwin", "arm64")
+	targetLibc = LibcMusl
+	if asset, ok := selectAsset(assetList("tool-darwin-arm64-gnu"), "darwin", "arm64"); !ok || asset.Name != "tool-darwin-arm64-gnu" {
+		t.Errorf("musl on macOS: got %q, %v", asset.Name, ok)
+	}
+}
+
+func TestSelectAssetARM(t *testing.T) {
+	tests := []struct {
+		version string
+		assets  []releaseAsset
+		want    string
+	}{
+		{"7", assetList("tool-linux-arm", "tool-linux-armv6", "tool-linux-armhf", "tool-linux-armv7", "tool-linux-arm64"), "tool-linux-armv7"},
+		{"7", assetList("tool-linux-arm", "tool-linux-armv6", "tool-linux-armhf"), "tool-linux-armhf"},
+		{"7", assetList("tool-linux-arm", "tool-linux-armv6.tar.gz"), "tool-linux-armv6.tar.gz"},
+		{"7", assetList("tool-linux-arm.tar.gz", "tool-linux-arm64"), "tool-linux-arm.tar.gz"},
+		{"6", assetList("tool-linux-armv7", "tool-linux-armv6.tar.gz", "tool-linux-arm"), "tool-linux-armv6.tar.gz"},
+		{"6", assetList("tool-linux-armv7", "tool-linux-arm.zip"), "tool-linux-arm.zip"},
+		{"6", assetList("tool-linux-armv7", "tool-linux-armhf"), ""},
+		{"6", assetList("tool-linux-armv6.sha256"), ""},
+		{"", assetList("tool-linux-armv7.zip", "tool-linux-armv6"), "tool-linux-armv6"},
+	}
+	for _, tt := range tests {
+		testPlatform(t, "linux", "arm")
+		targetARM = tt.version
+		asset, ok := selectAsset(tt.assets, "linux", "arm")
+		if ok != (tt.want != "") || asset.Name != tt.want {
+			t.Errorf("ARMv%s, %v: got %q, %v, want %q", tt.version, tt.assets, asset.Name, ok, tt.want)
+		}
+	}
+}
+
+func TestParseARMArch(t *testing.T) {
+	oldARM := targetARM
+	targetARM = "7"
+	t.Cleanup(func() { targetARM = oldARM })
+	tests := []struct{ arch, goarch, version string }{
+		{"armv6", "arm", "6"},
+		{"armv6l", "arm", "6"},
+		{"ARMv7", "arm", "7"},
+		{"armhf", "arm", "7"},
+		{"arm", "arm", "7"},
+		{"arm64", "arm64", "7"},
+	}
+	for _, tt := range tests {
+		goarch, version := parseARMArch(tt.arch)
+		if goarch != tt.goarch || version != tt.version {
+			t.Errorf("parseARMArch(%q) = %q, %q, want %q, %q", tt.arch, goarch, version, tt.goarch, tt.version)
+		}
+	}
+}
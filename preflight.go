@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// preflightAssets issues a HEAD for each app so missing or obviously bad
+// assets are reported before any bandwidth is spent on the real downloads.
+func preflightAssets(ctx context.Context, policy retryPolicy, apps []appInfo) []appInfo {
+	var valid []appInfo
+	for _, app := range apps {
+		if app.LocalPath != "" {
+			if info, err := os.Stat(app.LocalPath); err == nil {
+				app.Size = info.Size()
+			}
+			valid = append(valid, app)
+			continue
+		}
+
+		// The API's asset URLs redirect to storage that only takes GETs.
+		if app.AssetURL != "" && githubToken() != "" {
+			valid = append(valid, app)
+			continue
+		}
+
+		resp, err := policy.head(ctx, app.DownloadURL)
+		if err != nil {
+			console.errorf("Skipping %s, could not reach asset: %v", app.Name, err)
+			continue
+		}
+		resp.Body.Close()
+
+		if resp.StatusCode == http.StatusMethodNotAllowed || resp.StatusCode == http.StatusNotImplemented {
+			valid = append(valid, app)
+			continue
+		}
+		if resp.StatusCode != 200 {
+			console.errorf("Skipping %s, asset returned response code %d", app.Name, resp.StatusCode)
+			continue
+		}
+		if contentType := resp.Header.Get("Content-Type"); strings.HasPrefix(contentType, "text/html") {
+			console.errorf("Skipping %s, asset is an HTML page rather than a file", app.Name)
+			continue
+		}
+
+		app.Size = resp.ContentLength
+		valid = append(valid, app)
+	}
+
+	console.infof("%d of %d assets are ready to download (%s)", len(valid), len(apps), formatBytes(totalSize(valid)))
+	return valid
+}
+
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(n)/float64(div), "KMGTPE"[exp])
+}
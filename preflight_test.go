@@ -0,0 +1,25 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"testing"
+)
+
+func TestPreflightAssetsSameNameInTwoRepos(t *testing.T) {
+	policy := testAPI(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/a/tool-linux-amd64" {
+			http.NotFound(w, r)
+		}
+	}))
+	a := appInfo{Repo: "o/a", Name: "tool-linux-amd64", DownloadURL: apiBase + "/a/tool-linux-amd64"}
+	b := appInfo{Repo: "o/b", Name: "tool-linux-amd64", DownloadURL: apiBase + "/b/tool-linux-amd64"}
+
+	ready := preflightAssets(context.Background(), policy, []appInfo{a, b})
+	if !containsApp(ready, a) {
+		t.Errorf("%s's reachable asset isn't ready", a.Repo)
+	}
+	if containsApp(ready, b) {
+		t.Errorf("%s's missing asset counts as ready because %s has one with the same name", b.Repo, a.Repo)
+	}
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	progressBarWidth = 30
+
+	// progressInterval is how often progress is printed as plain text when
+	// stdout isn't a terminal.
+	progressInterval = 10 * time.Second
+)
+
+// outputMu keeps progress bars and the grouped output of finished downloads
+// from writing over each other. It also guards every progressBoard.
+var outputMu sync.Mutex
+
+// liveBoard is the board of the downloads under way, nil when there are
+// none, so other messages can move it out of their way.
+var liveBoard *progressBoard
+
+var spinnerFrames = []string{"|", "/", "-", "\\"}
+
+// progressBoard shows the downloads of a run. On a terminal each active
+// download gets a line of its own, redrawn in place, followed by the
+// progress of the whole run when there are several apps, and the board
+// collapses to that line when it's done. Elsewhere the same lines are
+// printed as plain text every progressInterval.
+type progressBoard struct {
+	tty     bool
+	apps    int
+	done    int
+	total   int64
+	written map[string]int64
+	bars    []*progressWriter
+	drawn   int
+	last    time.Time
+}
+
+// progressWriter counts the bytes of one download for its line on the board.
+// Without a Content-Length the line shows a spinner and a running byte count
+// instead of a bar.
+type progressWriter struct {
+	board   *progressBoard
+	name    string
+	total   int64
+	written int64
+	frame   int
+}
+
+func newProgressBoard(apps []appInfo) *progressBoard {
+	return &progressBoard{
+		tty:     isTerminal(output),
+		apps:    len(apps),
+		total:   totalSize(apps),
+		written: make(map[string]int64),
+		last:    time.Now(),
+	}
+}
+
+// showProgress reports whether downloads show their progress, which
+// --quiet turns off. With events on stdout it goes to stderr like every
+// other message.
+func showProgress() bool {
+	return verbosity >= LevelNormal
+}
+
+// start adds a line for a download. A retried download starts again from
+// zero.
+func (b *progressBoard) start(name string, total int64) *progressWriter {
+	outputMu.Lock()
+	defer outputMu.Unlock()
+	pw := &progressWriter{board: b, name: name, total: total}
+	b.bars = append(b.bars, pw)
+	b.written[name] = 0
+	b.draw(true)
+	return pw
+}
+
+// stop removes the line of a download that ended, however it ended.
+func (b *progressBoard) stop(pw *progressWriter) {
+	outputMu.Lock()
+	defer outputMu.Unlock()
+	for i, bar := range b.bars {
+		if bar == pw {
+			b.bars = append(b.bars[:i], b.bars[i+1:]...)
+			break
+		}
+	}
+	b.draw(true)
+}
+
+// finish counts an app as done, installed or not.
+func (b *progressBoard) finish() {
+	outputMu.Lock()
+	defer outputMu.Unlock()
+	b.done++
+	b.draw(true)
+}
+
+// close collapses the board to the progress of the whole run.
+func (b *progressBoard) close() {
+	outputMu.Lock()
+	defer outputMu.Unlock()
+	b.hide()
+	if b.apps > 1 {
+		fmt.Fprintln(output, b.line())
+	}
+	liveBoard = nil
+}
+
+func (pw *progressWriter) Write(p []byte) (int, error) {
+	outputMu.Lock()
+	defer outputMu.Unlock()
+	pw.written += int64(len(p))
+	pw.board.written[pw.name] = pw.written
+	pw.board.draw(pw.written == pw.total)
+	return len(p), nil
+}
+
+// draw redraws the board on a terminal, at most every 100ms unless forced,
+// and prints it as text elsewhere once progressInterval has passed. The
+// caller holds outputMu.
+func (b *progressBoard) draw(force bool) {
+	if b == nil {
+		return
+	}
+	if !b.tty {
+		if len(b.bars) == 0 || time.Since(b.last) < progressInterval {
+			return
+		}
+		b.last = time.Now()
+		for _, pw := range b.bars {
+			fmt.Fprintln(output, pw.line())
+		}
+		if b.apps > 1 {
+			fmt.Fprintln(output, b.line())
+		}
+		return
+	}
+
+	if !force && time.Since(b.last) < 100*time.Millisecond {
+		return
+	}
+	b.last = time.Now()
+	b.hide()
+	for _, pw := range b.bars {
+		pw.frame++
+		fmt.Fprintln(output, pw.line())
+	}
+	b.drawn = len(b.bars)
+	if b.apps > 1 {
+		fmt.Fprintln(output, b.line())
+		b.drawn++
+	}
+}
+
+// hide clears the board from a terminal so other output can take its place,
+// draw brings it back below that output. The caller holds outputMu.
+func (b *progressBoard) hide() {
+	if b == nil || b.drawn == 0 {
+		return
+	}
+	fmt.Fprintf(output, "\033[%dA\r\033[J", b.drawn)
+	b.drawn = 0
+}
+
+func (b *progressBoard) line() string {
+	var written int64
+	for _, n := range b.written {
+		written += n
+	}
+	if b.total <= 0 || written > b.total {
+		return fmt.Sprintf("%d/%d apps, %s in total", b.done, b.apps, formatBytes(written))
+	}
+	return fmt.Sprintf("%d/%d apps, %d%% of %s", b.done, b.apps, written*100/b.total, formatBytes(b.total))
+}
+
+func (pw *progressWriter) line() string {
+	if pw.total <= 0 {
+		return fmt.Sprintf("%s %s %s", pw.name, spinnerFrames[pw.frame%len(spinnerFrames)], formatBytes(pw.written))
+	}
+	filled := int(pw.written * progressBarWidth / pw.total)
+	if filled > progressBarWidth {
+		filled = progressBarWidth
+	}
+	bar := strings.Repeat("=", filled) + strings.Repeat(" ", progressBarWidth-filled)
+	return fmt.Sprintf("%s [%s] %3d%% %s / %s", pw.name, bar, pw.written*100/pw.total, formatBytes(pw.written), formatBytes(pw.total))
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// captureStdout returns what fn prints for people.
+func captureStdout(t *testing.T, fn func()) string {
+	t.Helper()
+	file, err := os.CreateTemp(t.TempDir(), "stdout")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	old := output
+	output = file
+	defer func() { output = old }()
+
+	fn()
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	out, err := io.ReadAll(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(out)
+}
+
+// screen is what a terminal shows of the board after out: whatever was
+// printed since it was last cleared.
+func screen(out string) []string {
+	if i := strings.LastIndex(out, "\033[J"); i >= 0 {
+		out = out[i+len("\033[J"):]
+	}
+	return strings.Split(strings.TrimSuffix(out, "\n"), "\n")
+}
+
+func TestProgressBoardConcurrentDownloads(t *testing.T) {
+	board := &progressBoard{tty: true, apps: 3, total: 300, written: make(map[string]int64)}
+	var a, b *progressWriter
+	out := captureStdout(t, func() {
+		a = board.start("tool-a", 100)
+		b = board.start("tool-b", 0)
+		board.last = time.Time{}
+		a.Write(make([]byte, 50))
+		board.last = time.Time{}
+		b.Write(make([]byte, 20))
+	})
+	lines := screen(out)
+	if len(lines) != 3 {
+		t.Fatalf("got %d lines on screen, want one per download and the run's: %q", len(lines), lines)
+	}
+	if !strings.HasPrefix(lines[0], "tool-a [===============               ]  50%") {
+		t.Errorf("first line is %q, want tool-a's bar", lines[0])
+	}
+	if !strings.HasPrefix(lines[1], "tool-b ") || !strings.HasSuffix(lines[1], "20 B") {
+		t.Errorf("second line is %q, want tool-b's spinner", lines[1])
+	}
+	if lines[2] != "0/3 apps, 23% of 300 B" {
+		t.Errorf("last line is %q, want the run's progress", lines[2])
+	}
+
+	out = captureStdout(t, func() {
+		board.stop(a)
+		board.finish()
+	})
+	if lines := screen(out); len(lines) != 2 || !strings.HasPrefix(lines[0], "tool-b ") || lines[1] != "1/3 apps, 23% of 300 B" {
+		t.Errorf("after tool-a finished the screen shows %q", lines)
+	}
+}
+
+func TestProgressBoardMovesForMessages(t *testing.T) {
+	board := &progressBoard{tty: true, apps: 2, total: 200, written: make(map[string]int64)}
+	liveBoard = board
+	defer func() { liveBoard = nil }()
+
+	out := captureStdout(t, func() {
+		board.start("tool-a", 100)
+		console.errorf("something went wrong")
+	})
+	message := strings.Index(out, "something went wrong")
+	redrawn := strings.LastIndex(out, "tool-a [")
+	if message < 0 || redrawn < message {
+		t.Errorf("the board wasn't redrawn below the message: %q", out)
+	}
+
+	out = captureStdout(t, board.close)
+	if lines := screen(out); len(lines) != 1 || lines[0] != "0/2 apps, 0% of 200 B" {
+		t.Errorf("the closed board shows %q, want the run's progress alone", lines)
+	}
+	if liveBoard != nil {
+		t.Error("close left the board live")
+	}
+}
+
+func TestProgressBoardWithoutTerminal(t *testing.T) {
+	board := &progressBoard{apps: 1, total: 100, written: make(map[string]int64), last: time.Now()}
+	var pw *progressWriter
+	out := captureStdout(t, func() {
+		pw = board.start("tool-a", 100)
+		pw.Write(make([]byte, 10))
+	})
+	if out != "" {
+		t.Errorf("printed %q before progressInterval passed", out)
+	}
+
+	board.last = time.Now().Add(-progressInterval)
+	out = captureStdout(t, func() { pw.Write(make([]byte, 10)) })
+	if strings.Contains(out, "\033") || !strings.HasPrefix(out, "tool-a [======") {
+		t.Errorf("printed %q, want a plain text line", out)
+	}
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const PathMarker = "# added by donut-utils"
+
+// pathExportPrefixes are the ways each supported shell spells the PATH line.
+var pathExportPrefixes = []string{"export PATH=$PATH:", "set -gx PATH $PATH ", `$env:Path += ";`}
+
+// parsePathExport returns the directory a donut-utils PATH line points at.
+// Lines written before the marker existed are recognized by the default
+// install directory name.
+func parsePathExport(line string) (string, bool) {
+	dir, marked := exportedDir(line)
+	if dir == "" {
+		return "", false
+	}
+	if !marked && filepath.Base(dir) != DownloadDir {
+		return "", false
+	}
+	return dir, true
+}
+
+// exportedDir returns the directory any PATH line appends, whoever wrote it,
+// and whether it carries the donut-utils marker.
+func exportedDir(line string) (string, bool) {
+	trimmed := strings.TrimSpace(line)
+	marked := strings.HasSuffix(trimmed, PathMarker)
+	trimmed = strings.TrimSpace(strings.TrimSuffix(trimmed, PathMarker))
+
+	for _, prefix := range pathExportPrefixes {
+		if rest, ok := strings.CutPrefix(trimmed, prefix); ok {
+			return strings.TrimSuffix(rest, `"`), marked
+		}
+	}
+	return "", false
+}
+
+// onPath reports whether dir is already on the live PATH, however it got
+// there, following symlinks on both sides.
+func onPath(dir string) bool {
+	want := resolvedDir(dir)
+	for _, entry := range filepath.SplitList(os.Getenv("PATH")) {
+		if entry != "" && resolvedDir(entry) == want {
+			return true
+		}
+	}
+	return false
+}
+
+func resolvedDir(dir string) string {
+	if resolved, err := filepath.EvalSymlinks(dir); err == nil {
+		dir = resolved
+	}
+	if abs, err := filepath.Abs(dir); err == nil {
+		dir = abs
+	}
+	return filepath.Clean(dir)
+}
+
+func pathAlreadyConfigured(shellrcPath, dir string) (bool, error) {
+	file, err := os.Open(shellrcPath)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if found, _ := exportedDir(scanner.Text()); found == dir {
+			return true, nil
+		}
+	}
+	return false, scanner.Err()
+}
+
+func prunePath(opts options) error {
+	current, err := installDir(opts)
+	if err != nil {
+		return fmt.Errorf("failed to resolve install directory: %w", err)
+	}
+
+	removed := 0
+	keptCurrent := make(map[string]bool)
+	err = rewriteShellrcs(func(shellrc, dir string) string {
+		if dir != current {
+			return "not the current install directory"
+		}
+		if _, err := os.Stat(dir); err != nil {
+			return "directory no longer exists"
+		}
+		if keptCurrent[shellrc] {
+			return "duplicate entry"
+		}
+		keptCurrent[shellrc] = true
+		return ""
+	}, &removed)
+
+	if err != nil {
+		return err
+	}
+	if removed == 0 {
+		console.infof("No stale donut-utils PATH entries found.")
+	}
+	return nil
+}
+
+// rewriteShellrcs removes the donut-utils PATH lines that reason gives a
+// reason for from every supported shell profile, counting them in removed.
+// A profile that can't be updated doesn't stop the others.
+func rewriteShellrcs(reason func(shellrc, dir string) string, removed *int) error {
+	failed := 0
+	for _, shell := range []string{ShellBash, ShellZsh, ShellFish, ShellPowerShell} {
+		shellrc := filepath.FromSlash(shellProfiles[shell])
+		shellrcPath, err := expandPath(filepath.Join("~", shellrc))
+		if err != nil {
+			return fmt.Errorf("failed to find your home directory: %w", err)
+		}
+		n, err := rewriteShellrc(shellrcPath, reason)
+		if err != nil {
+			console.errorf("Failed to update %s: %v", shellrc, err)
+			failed++
+			continue
+		}
+		*removed += n
+	}
+	if failed > 0 {
+		return exitErr(ExitPartial, fmt.Errorf("%d shell profile(s) could not be updated", failed))
+	}
+	return nil
+}
+
+func rewriteShellrc(shellrcPath string, reason func(shellrc, dir string) string) (int, error) {
+	info, err := os.Stat(shellrcPath)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	data, err := os.ReadFile(shellrcPath)
+	if err != nil {
+		return 0, err
+	}
+
+	var kept []string
+	removed := 0
+	for _, line := range strings.Split(string(data), "\n") {
+		dir, ok := parsePathExport(line)
+		if !ok {
+			kept = append(kept, line)
+			continue
+		}
+
+		why := reason(shellrcPath, dir)
+		if why == "" {
+			kept = append(kept, line)
+			continue
+		}
+
+		console.infof("Removed from %s: %s (%s)", filepath.Base(shellrcPath), strings.TrimSpace(line), why)
+		removed++
+	}
+
+	if removed == 0 {
+		return 0, nil
+	}
+	return removed, os.WriteFile(shellrcPath, []byte(strings.Join(kept, "\n")), info.Mode().Perm())
+}
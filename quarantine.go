@@ -0,0 +1,31 @@
+package main
+
+import (
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+const QuarantineAttr = "com.apple.quarantine"
+
+// removeQuarantine drops the quarantine attribute macOS puts on downloaded
+// files so Gatekeeper doesn't block the binary on first run. Files that were
+// never quarantined are left alone.
+func removeQuarantine(path string) error {
+	if runtime.GOOS != "darwin" {
+		return nil
+	}
+	out, err := exec.Command("xattr", "-d", QuarantineAttr, path).CombinedOutput()
+	if err != nil && !strings.Contains(string(out), "No such xattr") {
+		return fmt.Errorf("%v: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+func warnDequarantine() {
+	if runtime.GOOS != "darwin" {
+		return
+	}
+	console.infof("%s", red("--dequarantine is set: installed binaries will skip the Gatekeeper check macOS normally runs on downloaded files. Only use it with repos you trust."))
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+type rateLimitError struct {
+	Reset time.Time
+}
+
+func (e *rateLimitError) Error() string {
+	reset := e.Reset.Local()
+	format := "15:04:05 MST"
+	if reset.YearDay() != time.Now().YearDay() {
+		format = "Jan 2 15:04:05 MST"
+	}
+	wait := time.Until(reset).Round(time.Second)
+	if wait < 0 {
+		wait = 0
+	}
+	return fmt.Sprintf("GitHub API rate limit exhausted, it resets at %s (in %s)", reset.Format(format), wait)
+}
+
+// checkRateLimit spots responses that failed only because the rate limit ran
+// out, as opposed to a missing repo or a permissions problem.
+func checkRateLimit(resp *http.Response) *rateLimitError {
+	if resp.StatusCode != http.StatusForbidden && resp.StatusCode != http.StatusTooManyRequests {
+		return nil
+	}
+	if resp.Header.Get("X-RateLimit-Remaining") != "0" {
+		return nil
+	}
+
+	reset := time.Now().Add(time.Minute)
+	if seconds, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+		reset = time.Unix(seconds, 0)
+	}
+	return &rateLimitError{Reset: reset}
+}
+
+// waitForReset sleeps until the rate limit resets when that is no further
+// away than maxWait. It reports whether the caller can carry on.
+func waitForReset(ctx context.Context, limited *rateLimitError, maxWait time.Duration) bool {
+	wait := time.Until(limited.Reset) + time.Second
+	if wait > maxWait {
+		return false
+	}
+
+	console.infof("GitHub API rate limit exhausted, waiting %s for it to reset...", wait.Round(time.Second))
+	select {
+	case <-time.After(wait):
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
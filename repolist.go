@@ -0,0 +1,202 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// repoEntry is one repo to install along with any per-repo options. Plain
+// text lists only ever set Repo, Version, Filter and ExactAsset, the
+// structured YAML and JSON lists can set the rest.
+type repoEntry struct {
+	Repo       string `json:"repo" yaml:"repo"`
+	Version    string `json:"version,omitempty" yaml:"version,omitempty"`
+	Alias      string `json:"alias,omitempty" yaml:"alias,omitempty"`
+	Asset      string `json:"asset,omitempty" yaml:"asset,omitempty"`
+	Filter     string `json:"filter,omitempty" yaml:"filter,omitempty"`
+	Profile    string `json:"profile,omitempty" yaml:"profile,omitempty"`
+	InstallDir string `json:"install_dir,omitempty" yaml:"install_dir,omitempty"`
+	VerifyKey  string `json:"verify_key,omitempty" yaml:"verify_key,omitempty"`
+	Extract    string `json:"extract,omitempty" yaml:"extract,omitempty"`
+
+	Name        string `json:"name,omitempty" yaml:"name,omitempty"`
+	Description string `json:"description,omitempty" yaml:"description,omitempty"`
+
+	ExactAsset string `json:"-" yaml:"-"`
+	Line       int    `json:"-" yaml:"-"`
+
+	// LockedSHA256 is the digest a lockfile records for the installed
+	// binary.
+	LockedSHA256 string `json:"-" yaml:"-"`
+}
+
+var repoPattern = regexp.MustCompile(`^[A-Za-z0-9-]+/[A-Za-z0-9._-]+$`)
+
+const (
+	FormatAuto = "auto"
+	FormatText = "text"
+	FormatYAML = "yaml"
+	FormatJSON = "json"
+)
+
+// StdinRepoList as --repolist reads the repo list from stdin.
+const StdinRepoList = "-"
+
+// isRemoteRepoList reports whether --repolist is a URL to download the repo
+// list from, like a list curated for a team. Plain http:// URLs count too,
+// so fetchRepoList can refuse them instead of looking for a file.
+func isRemoteRepoList(path string) bool {
+	return strings.HasPrefix(path, "https://") || strings.HasPrefix(path, "http://")
+}
+
+// repoListFormat picks the format of a repo list from its extension unless
+// one was asked for explicitly.
+func repoListFormat(path, format string) string {
+	if format != "" && format != FormatAuto {
+		return format
+	}
+	if isRemoteRepoList(path) {
+		if u, err := url.Parse(path); err == nil {
+			path = u.Path
+		}
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return FormatJSON
+	case ".yaml", ".yml":
+		return FormatYAML
+	}
+	return FormatText
+}
+
+// loadRepoList reads the repo list at path, a URL, or stdin for
+// StdinRepoList, and keeps the entries that belong to profile. Entries
+// without a profile are always kept. Entries that aren't shaped like
+// owner/repo are left out and described in invalid, so one typo doesn't stop
+// the rest from installing.
+func loadRepoList(ctx context.Context, policy retryPolicy, path, format, profile string) (entries []repoEntry, invalid []string, err error) {
+	var data []byte
+	switch {
+	case path == StdinRepoList:
+		data, err = io.ReadAll(stdin)
+	case isRemoteRepoList(path):
+		data, err = fetchRepoList(ctx, policy, path)
+	default:
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	switch repoListFormat(path, format) {
+	// A misspelled key would otherwise be dropped without a word, so the
+	// structured lists reject keys they don't know.
+	case FormatJSON:
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		err = decoder.Decode(&entries)
+	case FormatYAML:
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		if err = decoder.Decode(&entries); err == io.EOF {
+			err = nil
+		}
+	case FormatText:
+		entries = parseRepoLines(string(data))
+	default:
+		err = fmt.Errorf("unknown repo list format %q, expected text, yaml or json", format)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var selected []repoEntry
+	for i, entry := range entries {
+		entry.Repo = strings.TrimSpace(entry.Repo)
+		where := fmt.Sprintf("%s: entry %d", path, i+1)
+		if entry.Line > 0 {
+			where = fmt.Sprintf("%s:%d", path, entry.Line)
+		}
+		if entry.Repo == "" {
+			invalid = append(invalid, where+": no repo given")
+			continue
+		}
+		if !repoPattern.MatchString(entry.Repo) && !gitLabPattern.MatchString(entry.Repo) {
+			invalid = append(invalid, fmt.Sprintf("%s: invalid repo %q, expected owner/repo or gitlab:group/project", where, entry.Repo))
+			continue
+		}
+		if entry.Profile != "" && entry.Profile != profile {
+			continue
+		}
+		if entry.Alias != "" {
+			entry.Alias = filepath.Base(entry.Alias)
+		}
+		if entry.InstallDir != "" {
+			entry.InstallDir, err = expandPath(entry.InstallDir)
+			if err != nil {
+				return nil, nil, err
+			}
+		}
+		selected = append(selected, entry)
+	}
+	return selected, invalid, nil
+}
+
+// fetchRepoList downloads a remote repo list. A list decides what gets
+// installed and where, so it's only accepted over HTTPS, redirects included.
+func fetchRepoList(ctx context.Context, policy retryPolicy, listURL string) ([]byte, error) {
+	if !strings.HasPrefix(listURL, "https://") {
+		return nil, fmt.Errorf("refusing to download the repo list from %s over plain HTTP, where anyone on the network could change it, use an https:// URL", listURL)
+	}
+	resp, err := policy.get(ctx, listURL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.Request != nil && resp.Request.URL.Scheme != "https" {
+		return nil, fmt.Errorf("refusing the repo list from %s, it was redirected to plain HTTP", listURL)
+	}
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("%s returned response code %d", listURL, resp.StatusCode)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+func parseRepoLines(data string) []repoEntry {
+	var entries []repoEntry
+	for i, line := range strings.Split(data, "\n") {
+		entry := parseRepoLine(line)
+		if entry.Repo == "" {
+			continue
+		}
+		entry.Line = i + 1
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// parseRepoLine reads a repo list line of the form owner/repo, optionally
+// pinned to a release tag as owner/repo@v1.2.3 and naming an exact asset as
+// owner/repo!!asset-name. A trailing | pattern, as in owner/repo | *cli*,
+// only considers assets matching the glob. Blank and # comment lines give
+// an empty repo.
+func parseRepoLine(line string) repoEntry {
+	line = strings.TrimSpace(line)
+	if strings.HasPrefix(line, "#") {
+		return repoEntry{}
+	}
+	line, filter, _ := strings.Cut(line, "|")
+	repo, asset, _ := strings.Cut(line, "!!")
+	repo, tag, _ := strings.Cut(strings.TrimSpace(repo), "@")
+	return repoEntry{Repo: strings.TrimSpace(repo), Version: strings.TrimSpace(tag), ExactAsset: strings.TrimSpace(asset), Filter: strings.TrimSpace(filter)}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFetchRepoList(t *testing.T) {
+	plain := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, "o/evil")
+	}))
+	defer plain.Close()
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/team.txt":
+			fmt.Fprintln(w, "o/tool")
+		case "/downgrade.txt":
+			http.Redirect(w, r, plain.URL+"/team.txt", http.StatusFound)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	policy := retryPolicy{Fetcher: server.Client()}
+	ctx := context.Background()
+
+	data, err := fetchRepoList(ctx, policy, server.URL+"/team.txt")
+	if err != nil || string(data) != "o/tool\n" {
+		t.Errorf("got %q, %v", data, err)
+	}
+	if _, err := fetchRepoList(ctx, policy, server.URL+"/missing.txt"); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("missing list: got error %v, want a 404", err)
+	}
+	if _, err := fetchRepoList(ctx, policy, plain.URL+"/team.txt"); err == nil || !strings.Contains(err.Error(), "plain HTTP") {
+		t.Errorf("http:// list: got error %v, want it refused", err)
+	}
+	if _, err := fetchRepoList(ctx, policy, server.URL+"/downgrade.txt"); err == nil || !strings.Contains(err.Error(), "redirected to plain HTTP") {
+		t.Errorf("list redirected to http://: got error %v, want it refused", err)
+	}
+}
+
+func TestLoadRepoListUnknownKeys(t *testing.T) {
+	tests := []struct {
+		file    string
+		data    string
+		wantErr bool
+	}{
+		{"repos.yaml", "- repo: o/a\n  filter: musl\n", false},
+		{"repos.yaml", "- repo: o/a\n  fitler: musl\n", true},
+		{"repos.yaml", "", false},
+		{"repos.json", `[{"repo": "o/a", "alias": "a"}]`, false},
+		{"repos.json", `[{"repo": "o/a", "aliass": "a"}]`, true},
+	}
+	for _, tt := range tests {
+		path := filepath.Join(t.TempDir(), tt.file)
+		if err := os.WriteFile(path, []byte(tt.data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		_, _, err := loadRepoList(context.Background(), retryPolicy{}, path, FormatAuto, "")
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s %q: got error %v, want one: %v", tt.file, tt.data, err, tt.wantErr)
+		}
+	}
+}
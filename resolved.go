@@ -0,0 +1,48 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const ResolvedFile = "resolved.json"
+
+type resolvedSet struct {
+	ResolvedAt time.Time `json:"resolved_at"`
+	Apps       []appInfo `json:"apps"`
+}
+
+func saveResolved(dir string, apps []appInfo) error {
+	data, err := json.MarshalIndent(resolvedSet{ResolvedAt: time.Now().UTC(), Apps: apps}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, ResolvedFile), data, 0644)
+}
+
+// loadResolved reads the apps saved by --resolve-only, refusing ones older
+// than ttl since the releases they point at may have moved on.
+func loadResolved(dir string, ttl time.Duration) ([]appInfo, error) {
+	data, err := os.ReadFile(filepath.Join(dir, ResolvedFile))
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("no resolved apps found, run with --resolve-only first")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var resolved resolvedSet
+	err = json.Unmarshal(data, &resolved)
+	if err != nil {
+		return nil, err
+	}
+
+	age := time.Since(resolved.ResolvedAt)
+	if ttl > 0 && age > ttl {
+		return nil, fmt.Errorf("resolved apps are %s old, older than --resolved-ttl %s, run with --resolve-only again", age.Round(time.Second), ttl)
+	}
+	return resolved.Apps, nil
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+)
+
+const ActivateScript = "activate"
+
+// installDir is --install-dir when it's set, otherwise the scope's default.
+func installDir(opts options) (string, error) {
+	if opts.InstallDir != "" {
+		dir, err := expandPath(opts.InstallDir)
+		if err != nil {
+			return "", err
+		}
+		return filepath.Abs(dir)
+	}
+	if opts.Scope == ScopeProject {
+		return filepath.Abs(DownloadDir)
+	}
+	return expandPath(filepath.Join("~", DownloadDir))
+}
+
+// expandPath expands environment variables in p, then a leading ~ to the
+// home directory or ~user to that user's.
+func expandPath(p string) (string, error) {
+	p = os.ExpandEnv(p)
+	if !strings.HasPrefix(p, "~") {
+		return p, nil
+	}
+
+	name, rest := p[1:], ""
+	if i := strings.IndexFunc(name, func(r rune) bool { return r < utf8.RuneSelf && os.IsPathSeparator(uint8(r)) }); i >= 0 {
+		name, rest = name[:i], name[i+1:]
+	}
+	if name == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		return filepath.Join(home, rest), nil
+	}
+	usr, err := user.Lookup(name)
+	if err != nil {
+		return "", fmt.Errorf("failed to expand %s: %w", p, err)
+	}
+	return filepath.Join(usr.HomeDir, rest), nil
+}
+
+var repoListNames = []string{ReposList, "repolist.yaml", "repolist.yml", "repolist.json"}
+
+// profileListNames are the names of a profile's own repo list, like
+// repolist.work.txt for --profile work.
+func profileListNames(profile string) []string {
+	var names []string
+	for _, name := range repoListNames {
+		ext := filepath.Ext(name)
+		names = append(names, strings.TrimSuffix(name, ext)+"."+profile+ext)
+	}
+	return names
+}
+
+// reposListPath prefers a list kept inside the project's .donut-utils so a
+// project can pin its own tools, falling back to the current directory. A
+// profile's own list wins over the shared one, and the plain text list wins
+// when more than one format is present. --repolist overrides all of it.
+func reposListPath(opts options) string {
+	if opts.RepoList == StdinRepoList || isRemoteRepoList(opts.RepoList) {
+		return opts.RepoList
+	}
+	if opts.RepoList != "" {
+		if list, err := expandPath(opts.RepoList); err == nil {
+			return list
+		}
+		return opts.RepoList
+	}
+
+	var dirs []string
+	if opts.Scope == ScopeProject {
+		dirs = append(dirs, DownloadDir)
+	}
+	dirs = append(dirs, ".")
+
+	names := repoListNames
+	if opts.Profile != "" {
+		names = append(profileListNames(opts.Profile), names...)
+	}
+	for _, dir := range dirs {
+		for _, name := range names {
+			candidate := filepath.Join(dir, name)
+			if _, err := os.Stat(candidate); err == nil {
+				return candidate
+			}
+		}
+	}
+	return ReposList
+}
+
+func writeActivateScript(dir string) {
+	script := "# generated by donut-utils, source this file to use the project's tools\n" + pathExportLine(dir) + "\n"
+	scriptPath := filepath.Join(dir, ActivateScript)
+	err := os.WriteFile(scriptPath, []byte(script), 0644)
+	if err != nil {
+		console.errorf("Failed to write activation script: %v", err)
+		return
+	}
+
+	console.infof("Wrote project activation script to: %s", scriptPath)
+	console.infof("\nTo use the project's tools in your current shell, run:")
+	console.infof("\nsource %s", displayPath(scriptPath))
+	console.infof("\nIf you use direnv, add that line to your .envrc instead.")
+}
+
+// displayPath shortens path to be relative to the current directory when
+// it's inside it, as for a project's .donut-utils.
+func displayPath(path string) string {
+	wd, err := os.Getwd()
+	if err != nil {
+		return path
+	}
+	rel, err := filepath.Rel(wd, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return path
+	}
+	return rel
+}
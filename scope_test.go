@@ -0,0 +1,34 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWriteActivateScriptNamesItsPath(t *testing.T) {
+	dir := t.TempDir()
+	out := captureStdout(t, func() { writeActivateScript(dir) })
+	want := "source " + filepath.Join(dir, ActivateScript)
+	if !strings.Contains(out, want) {
+		t.Errorf("output doesn't tell to %q: %q", want, out)
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+	project := filepath.Join(dir, DownloadDir)
+	if err := os.Mkdir(project, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	out = captureStdout(t, func() { writeActivateScript(project) })
+	if want := "source " + filepath.Join(DownloadDir, ActivateScript) + "\n"; !strings.Contains(out, want) {
+		t.Errorf("output doesn't tell to %q: %q", want, out)
+	}
+}
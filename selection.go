@@ -0,0 +1,125 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// parseSelection turns the answer to the download prompt into the apps to
+// install: yes or all for every app, no, none or nothing for none, or a
+// comma-separated list of the numbers shown in the listing and ranges of
+// them like 3-5.
+func parseSelection(answer string, apps []appInfo) ([]appInfo, error) {
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	switch answer {
+	case "yes", "y", "all":
+		return apps, nil
+	case "", "no", "n", "none":
+		return nil, nil
+	}
+
+	var selected []appInfo
+	seen := make(map[int]bool)
+	for _, field := range strings.Split(answer, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		first, last, isRange := strings.Cut(field, "-")
+		if !isRange {
+			last = first
+		}
+		from, err := strconv.Atoi(strings.TrimSpace(first))
+		if err != nil || from < 1 || from > len(apps) {
+			return nil, fmt.Errorf("%q is not one of the listed numbers, 1 to %d", field, len(apps))
+		}
+		to, err := strconv.Atoi(strings.TrimSpace(last))
+		if err != nil || to < from || to > len(apps) {
+			return nil, fmt.Errorf("%q is not a range of the listed numbers, 1 to %d", field, len(apps))
+		}
+		for n := from; n <= to; n++ {
+			if !seen[n] {
+				seen[n] = true
+				selected = append(selected, apps[n-1])
+			}
+		}
+	}
+	return selected, nil
+}
+
+func containsApp(apps []appInfo, app appInfo) bool {
+	for _, candidate := range apps {
+		if candidate.Repo == app.Repo && candidate.Name == app.Name {
+			return true
+		}
+	}
+	return false
+}
+
+// stdin is shared by every prompt so input buffered by one isn't lost to the
+// next.
+var stdin = bufio.NewReader(os.Stdin)
+
+// waitForEnter gives an interactive user the chance to back out before
+// anything happens. Piped input or output goes straight through. It reports
+// whether to carry on.
+func waitForEnter() bool {
+	if !isTerminal(os.Stdin) || !isTerminal(output) {
+		return true
+	}
+	fmt.Fprintln(output, "\nPress Enter to continue, or CTRL C to abort.")
+	_, err := stdin.ReadString('\n')
+	return err == nil
+}
+
+func totalSize(apps []appInfo) int64 {
+	var total int64
+	for _, app := range apps {
+		if app.Size > 0 {
+			total += app.Size
+		}
+	}
+	return total
+}
+
+// confirm asks a yes or no question. Anything but yes, including no input at
+// all, is a no.
+func confirm(question string) bool {
+	fmt.Fprintln(output, question)
+	line, _ := stdin.ReadString('\n')
+	answer := strings.ToLower(strings.TrimSpace(line))
+	return answer == "yes" || answer == "y"
+}
+
+// confirmDownload asks again before a download bigger than --confirm-above.
+func confirmDownload(total, limit int64) bool {
+	return confirm(fmt.Sprintf("\nThat's %s to download, more than %s. Download anyway? Enter yes to continue.", formatBytes(total), formatBytes(limit)))
+}
+
+// confirmOverwrite asks before replacing a file donut-utils didn't install
+// for the app.
+func confirmOverwrite(app appInfo, target string) bool {
+	return confirm(fmt.Sprintf("\n%s already exists and wasn't installed for %s. Overwrite it? Enter yes to overwrite, anything else skips it.", target, app.displayName()))
+}
+
+// promptSelection asks which apps to download until it gets an answer it
+// understands.
+func promptSelection(apps []appInfo) ([]appInfo, error) {
+	for {
+		fmt.Fprintln(output, "\n\nWhich applications do you want to download? Enter yes or all for every one, numbers and ranges like 1,3-5, or none.")
+		line, err := stdin.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+
+		selected, err := parseSelection(line, apps)
+		if err != nil {
+			fmt.Fprintln(output, red(err.Error()))
+			continue
+		}
+		return selected, nil
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
+const SelfRepo = "donuts-are-good/donut-utils"
+
+// Version is the release this binary was built from, set when building a
+// release with -ldflags "-X main.Version=v1.2.3".
+var Version = "dev"
+
+func printVersion() {
+	fmt.Fprintf(output, "donut-utils %s (%s/%s)\n", Version, runtime.GOOS, runtime.GOARCH)
+}
+
+// selfUpdate replaces the running executable with donut-utils' latest
+// release when it's newer. Windows won't replace a running executable, so
+// there the release is saved next to it with a .new extension instead.
+func selfUpdate(opts options) error {
+	if Version == "dev" {
+		return fmt.Errorf("this is a development build, --self-update only works for release builds")
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to find the running executable: %w", err)
+	}
+	exe, err = filepath.EvalSymlinks(exe)
+	if err != nil {
+		return fmt.Errorf("failed to find the running executable: %w", err)
+	}
+
+	ctx := context.Background()
+	entry := repoEntry{Repo: SelfRepo}
+	release, err := fetchRelease(ctx, opts.API, entry)
+	if err != nil {
+		return exitErr(ExitNetwork, fmt.Errorf("failed to get latest release: %w", err))
+	}
+	if compareVersions(release.Version, Version) <= 0 {
+		console.infof("donut-utils %s is up to date.", Version)
+		return nil
+	}
+
+	app, _ := matchRelease(ctx, opts, entry, release, nil)
+	if app == nil {
+		return fmt.Errorf("donut-utils %s has no asset for %s/%s", release.Version, targetOS, targetArch)
+	}
+	app.BinaryName = filepath.Base(exe)
+	if runtime.GOOS == "windows" {
+		app.BinaryName += ".new"
+	}
+
+	inst := &installer{
+		opts: opts,
+		dir:  filepath.Dir(exe),
+		wd:   &watchdog{idle: opts.StallTimeout, retries: opts.StallRetries, policy: opts.Download, progress: showProgress()},
+	}
+	err = inst.installAll(ctx, []appInfo{*app})[0]
+	if errors.Is(err, errUpToDate) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update donut-utils: %w", err)
+	}
+
+	if runtime.GOOS == "windows" {
+		// Printed even with --quiet, the update isn't done until this is run.
+		console.errorf("\nSaved donut-utils %s next to the running one. To finish updating, close donut-utils and run:", release.Version)
+		console.errorf("\n    Move-Item -Force \"%s.new\" \"%s\"\n", exe, exe)
+		return nil
+	}
+	console.infof("%s", green(fmt.Sprintf("Updated donut-utils from %s to %s", Version, release.Version)))
+	return nil
+}
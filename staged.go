@@ -0,0 +1,49 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// StagedPrefix starts the names of downloads and extracted binaries staged
+// next to their target, so the ones a killed run left behind can be told
+// apart from everything else in the install directory.
+const StagedPrefix = ".donut-utils-staged-"
+
+// StaleStagedAge is how long a staged file has to go unmodified before it's
+// taken for a leftover rather than a download another run is still working
+// on.
+const StaleStagedAge = time.Hour
+
+var sweptDirs sync.Map
+
+// sweepStaged removes the staged files a run that was killed or crashed
+// left in dir, once per run and directory. SIGINT and SIGTERM clean up
+// after themselves, this catches the rest.
+func sweepStaged(dir string) {
+	if _, done := sweptDirs.LoadOrStore(dir, true); done {
+		return
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() || !strings.HasPrefix(entry.Name(), StagedPrefix) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil || time.Since(info.ModTime()) < StaleStagedAge {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		if err := os.Remove(path); err != nil {
+			console.errorf("Failed to remove %s, left over from an interrupted run: %v", path, err)
+			continue
+		}
+		console.debugf("Removed %s, left over from an interrupted run", path)
+	}
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSweepStaged(t *testing.T) {
+	dir := t.TempDir()
+	old := time.Now().Add(-2 * StaleStagedAge)
+	files := map[string]bool{
+		StagedPrefix + "tool-123.tar.gz": false,
+		StagedPrefix + "tool-123":        false,
+		StagedPrefix + "other-456":       true,
+		".tool-789":                      true,
+		"tool":                           true,
+	}
+	for name := range files {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte("x"), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if name == StagedPrefix+"other-456" {
+			// Still being written by another run.
+			continue
+		}
+		if err := os.Chtimes(path, old, old); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	sweepStaged(dir)
+	for name, kept := range files {
+		_, err := os.Stat(filepath.Join(dir, name))
+		if kept && err != nil {
+			t.Errorf("%s was removed", name)
+		}
+		if !kept && err == nil {
+			t.Errorf("%s was left behind", name)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+)
+
+type outcome struct {
+	App    string
+	Reason string
+}
+
+// runSummary collects what happened to every app so the end of the run can
+// show it in one place instead of scattered through the output.
+type runSummary struct {
+	installed []string
+	skipped   []outcome
+	refused   []outcome
+	failed    []outcome
+}
+
+// newRunSummary starts from the repos discovery already gave up on.
+func newRunSummary(lookupFailed map[string]bool, unmatched []unmatchedRepo) *runSummary {
+	s := &runSummary{}
+	var repos []string
+	for repo := range lookupFailed {
+		repos = append(repos, repo)
+	}
+	sort.Strings(repos)
+	for _, repo := range repos {
+		s.fail(repo, "looking up its release failed")
+	}
+	for _, missing := range unmatched {
+		if missing.Refused != "" {
+			s.refuse(missing.Repo, missing.Refused)
+			continue
+		}
+		s.skip(missing.Repo, fmt.Sprintf("no asset for %s/%s", targetOS, targetArch))
+	}
+	return s
+}
+
+func (s *runSummary) install(app string) {
+	s.installed = append(s.installed, app)
+}
+
+func (s *runSummary) skip(app, reason string) {
+	s.skipped = append(s.skipped, outcome{App: app, Reason: reason})
+}
+
+func (s *runSummary) refuse(app, reason string) {
+	s.refused = append(s.refused, outcome{App: app, Reason: reason})
+}
+
+func (s *runSummary) fail(app, reason string) {
+	s.failed = append(s.failed, outcome{App: app, Reason: reason})
+}
+
+// hasFailures reports whether any app failed or was refused, either of
+// which makes the run exit non-zero.
+func (s *runSummary) hasFailures() bool {
+	return len(s.failed) > 0 || len(s.refused) > 0
+}
+
+func (s *runSummary) print() {
+	fmt.Fprintf(output, "\nSummary: %d installed, %d skipped, %d refused, %d failed\n", len(s.installed), len(s.skipped), len(s.refused), len(s.failed))
+	for _, app := range s.installed {
+		fmt.Fprintln(output, green("  installed  "+app))
+	}
+	for _, o := range s.skipped {
+		fmt.Fprintf(output, "  skipped    %s (%s)\n", o.App, o.Reason)
+	}
+	for _, o := range s.refused {
+		fmt.Fprintln(output, red(fmt.Sprintf("  refused    %s (%s)", o.App, o.Reason)))
+	}
+	for _, o := range s.failed {
+		fmt.Fprintln(output, red(fmt.Sprintf("  failed     %s (%s)", o.App, o.Reason)))
+	}
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// trustedAuthors maps owner/repo to the GitHub logins allowed to publish
+// releases for it. Repos without an entry are not checked.
+type trustedAuthors map[string][]string
+
+func (t trustedAuthors) String() string {
+	var pairs []string
+	for repo, logins := range t {
+		for _, login := range logins {
+			pairs = append(pairs, repo+"="+login)
+		}
+	}
+	return strings.Join(pairs, ",")
+}
+
+// Set accepts a comma-separated list so the whole allowlist can come from a
+// single environment variable.
+func (t trustedAuthors) Set(value string) error {
+	for _, pair := range strings.Split(value, ",") {
+		repo, login, ok := strings.Cut(pair, "=")
+		repo = strings.TrimSpace(repo)
+		login = strings.TrimSpace(login)
+		if !ok || repo == "" || login == "" {
+			return fmt.Errorf("expected owner/repo=login, got %q", pair)
+		}
+		t[repo] = append(t[repo], login)
+	}
+	return nil
+}
+
+func (t trustedAuthors) allows(repo, login string) bool {
+	logins, ok := t[repo]
+	if !ok {
+		return true
+	}
+	for _, trusted := range logins {
+		if strings.EqualFold(trusted, login) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// uninstall removes everything the manifest says was installed, the
+// bookkeeping files next to it and, unless --keep-path is set, the PATH lines
+// pointing at the install directory. Other lines in the shell profiles are
+// left alone.
+func uninstall(opts options) error {
+	dir, err := installDir(opts)
+	if err != nil {
+		return fmt.Errorf("failed to resolve install directory: %w", err)
+	}
+
+	installed, err := loadManifest(dir)
+	if err != nil {
+		return fmt.Errorf("failed to read install manifest: %w", err)
+	}
+
+	removedApps, failed := installed.removeInstalled(func(string, installedApp) bool { return true })
+
+	// The files that couldn't be removed keep their entries, so a later
+	// uninstall can still find them.
+	bookkeeping := []string{FailedFile, ResolvedFile, ActivateScript, CacheFile}
+	if len(installed) == 0 {
+		bookkeeping = append(bookkeeping, ManifestFile)
+	} else if err := installed.save(dir); err != nil {
+		console.errorf("Failed to save install manifest: %v", err)
+	}
+	for _, name := range bookkeeping {
+		if err := os.Remove(filepath.Join(dir, name)); err == nil {
+			console.infof("Removed %s", filepath.Join(dir, name))
+		}
+	}
+
+	removed := 0
+	var pathErr error
+	if !opts.KeepPath {
+		pathErr = rewriteShellrcs(func(shellrc, exported string) string {
+			if exported != dir {
+				return ""
+			}
+			return "uninstalled"
+		}, &removed)
+	}
+
+	if os.Remove(dir) == nil {
+		console.infof("Removed %s", dir)
+	}
+	if removedApps == 0 && failed == 0 && removed == 0 {
+		console.infof("Nothing installed by donut-utils was found in %s", dir)
+	}
+	if failed > 0 {
+		return exitErr(ExitPartial, fmt.Errorf("%d file(s) could not be removed", failed))
+	}
+	return pathErr
+}
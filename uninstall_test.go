@@ -0,0 +1,64 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestUninstallKeepsWhatItCouldNotRemove(t *testing.T) {
+	dir := t.TempDir()
+	installed := filepath.Join(dir, "tool")
+	// A path below a regular file can't be removed, and isn't missing either.
+	stuck := filepath.Join(dir, "blocker", "stuck")
+	for _, name := range []string{"tool", "blocker", CacheFile, FailedFile} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	m := manifest{installed: {Path: installed}, stuck: {Path: stuck}}
+	if err := m.save(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	var exit *exitError
+	if err := uninstall(options{InstallDir: dir, KeepPath: true}); !errors.As(err, &exit) || exit.code != ExitPartial {
+		t.Errorf("got %v, want exit status %d", err, ExitPartial)
+	}
+	left, err := loadManifest(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(left) != 1 || left[stuck].Path != stuck {
+		t.Errorf("the manifest kept %v, want only the file that couldn't be removed", left)
+	}
+	for _, name := range []string{"tool", CacheFile, FailedFile} {
+		if _, err := os.Stat(filepath.Join(dir, name)); !os.IsNotExist(err) {
+			t.Errorf("%s is still there", name)
+		}
+	}
+}
+
+func TestUninstallRemovesTheDirectory(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), ".donut-utils")
+	if err := os.Mkdir(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	tool := filepath.Join(dir, "tool")
+	for _, name := range []string{"tool", CacheFile, ResolvedFile} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := (manifest{tool: {Path: tool}}).save(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := uninstall(options{InstallDir: dir, KeepPath: true}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Errorf("%s is still there", dir)
+	}
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"strings"
+)
+
+// unmatchedRepo is a repo whose release was found but not installed: it has
+// no asset for this platform, or it was refused, in which case Refused says
+// why.
+type unmatchedRepo struct {
+	Repo    string
+	Assets  []string
+	Refused string
+}
+
+// printInvalid lists the repo list entries that were skipped for not being
+// shaped like owner/repo.
+func printInvalid(invalid []string) {
+	if len(invalid) == 0 {
+		return
+	}
+	console.infof("\n%d line(s) of the repo list were skipped:", len(invalid))
+	for _, line := range invalid {
+		console.infof("%s", red("  "+line))
+	}
+}
+
+func printUnmatched(all []unmatchedRepo, detailed bool) {
+	var unmatched []unmatchedRepo
+	for _, missing := range all {
+		if missing.Refused == "" {
+			unmatched = append(unmatched, missing)
+		}
+	}
+	if len(unmatched) == 0 {
+		return
+	}
+
+	if !detailed {
+		console.infof("\n%d repo(s) have a release but no asset for %s/%s, run with --report-unmatched to see them.", len(unmatched), targetOS, targetArch)
+		return
+	}
+
+	console.infof("\n\nThe following repos have a release but no asset for %s/%s:", targetOS, targetArch)
+	for _, missing := range unmatched {
+		if len(missing.Assets) == 0 {
+			console.infof("\n%s: release has no assets", missing.Repo)
+			continue
+		}
+		console.infof("\n%s: available assets are %s", missing.Repo, strings.Join(missing.Assets, ", "))
+	}
+}
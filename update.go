@@ -0,0 +1,119 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// pendingUpdates keeps the apps whose release is newer than the installed
+// version, or that aren't installed at all, and counts the rest. A pinned
+// app is pending whenever it's installed at another version than its pin,
+// newer or older, so changing the pin moves the install. Pinned apps
+// already at their pin are counted apart from the ones up to date.
+func pendingUpdates(dir string, apps []appInfo) (pending []appInfo, upToDate, pinned int, err error) {
+	installed, err := loadManifest(dir)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+
+	for _, app := range apps {
+		current, ok := installed[app.installPath(dir)]
+		switch {
+		case ok && app.Pinned && app.Version == current.Version:
+			console.infof("Keeping %s at %s, it's pinned in the repo list (use --ignore-pins to update it to the latest release)", app.Repo, app.Version)
+			pinned++
+		case ok && !app.Pinned && compareVersions(app.Version, current.Version) <= 0:
+			upToDate++
+		default:
+			pending = append(pending, app)
+		}
+	}
+	return pending, upToDate, pinned, nil
+}
+
+// unpin drops the release pins of entries, for update --ignore-pins.
+func unpin(entries []repoEntry) []repoEntry {
+	unpinned := make([]repoEntry, len(entries))
+	for i, entry := range entries {
+		entry.Version = ""
+		unpinned[i] = entry
+	}
+	return unpinned
+}
+
+// printOutdated lists the installed apps whose release is newer than the
+// installed version, for the outdated command.
+func printOutdated(dir string, apps []appInfo) error {
+	installed, err := loadManifest(dir)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(output, 0, 0, 2, ' ', 0)
+	outdated := false
+	for _, app := range apps {
+		current, ok := installed[app.installPath(dir)]
+		if !ok || compareVersions(app.Version, current.Version) <= 0 {
+			continue
+		}
+		if !outdated {
+			fmt.Fprintln(w, "NAME\tINSTALLED\tLATEST")
+			outdated = true
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", app.displayName(), current.Version, app.Version)
+	}
+	w.Flush()
+	if !outdated {
+		fmt.Fprintln(output, "Every installed app is up to date.")
+	}
+	return nil
+}
+
+// compareVersions orders release tags by semantic version, so v1.10.0 comes
+// after v1.9.0. A pre-release sorts before its release, and parts that
+// aren't numbers fall back to comparing as strings.
+func compareVersions(a, b string) int {
+	a, aPre, _ := strings.Cut(strings.TrimPrefix(a, "v"), "-")
+	b, bPre, _ := strings.Cut(strings.TrimPrefix(b, "v"), "-")
+
+	aParts, bParts := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(aParts) || i < len(bParts); i++ {
+		aPart, bPart := "0", "0"
+		if i < len(aParts) {
+			aPart = aParts[i]
+		}
+		if i < len(bParts) {
+			bPart = bParts[i]
+		}
+		if c := comparePart(aPart, bPart); c != 0 {
+			return c
+		}
+	}
+
+	switch {
+	case aPre == bPre:
+		return 0
+	case aPre == "":
+		return 1
+	case bPre == "":
+		return -1
+	}
+	return comparePart(aPre, bPre)
+}
+
+func comparePart(a, b string) int {
+	aNum, aErr := strconv.Atoi(a)
+	bNum, bErr := strconv.Atoi(b)
+	if aErr == nil && bErr == nil {
+		switch {
+		case aNum < bNum:
+			return -1
+		case aNum > bNum:
+			return 1
+		}
+		return 0
+	}
+	return strings.Compare(a, b)
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestPendingUpdates(t *testing.T) {
+	dir := t.TempDir()
+	apps := []appInfo{
+		{Repo: "o/newer", Name: "newer", Version: "v1.1.0"},
+		{Repo: "o/current", Name: "current", Version: "v1.0.0"},
+		{Repo: "o/new", Name: "new", Version: "v1.0.0"},
+		{Repo: "o/held", Name: "held", Version: "v1.0.0", Pinned: true},
+		{Repo: "o/repinned", Name: "repinned", Version: "v0.9.0", Pinned: true},
+	}
+	m := make(manifest)
+	for _, name := range []string{"newer", "current", "held", "repinned"} {
+		m[filepath.Join(dir, name)] = installedApp{Version: "v1.0.0"}
+	}
+	if err := m.save(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	pending, upToDate, pinned, err := pendingUpdates(dir, apps)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, app := range pending {
+		names = append(names, app.Name)
+	}
+	if want := []string{"newer", "new", "repinned"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("pending %v, want %v", names, want)
+	}
+	if upToDate != 1 || pinned != 1 {
+		t.Errorf("got %d up to date and %d pinned, want 1 and 1", upToDate, pinned)
+	}
+}
+
+func TestUnpin(t *testing.T) {
+	entries := []repoEntry{{Repo: "o/a", Version: "v1"}, {Repo: "o/b"}}
+	unpinned := unpin(entries)
+	if unpinned[0].Version != "" || unpinned[0].Repo != "o/a" {
+		t.Errorf("got %+v, want o/a without its pin", unpinned[0])
+	}
+	if entries[0].Version != "v1" {
+		t.Error("unpin changed the entries it was given")
+	}
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+)
+
+// errUpToDate is returned by downloadAndStore when the installed binary is
+// already the one the release would install.
+var errUpToDate = errors.New("already up to date")
+
+// alreadyInstalled reports whether target already holds what app would
+// install. A binary from a lockfile has to have the digest it records. When the asset is a plain binary with a pinned or release
+// checksum, the installed file is checked against it. An archive's checksum
+// says nothing about the binary inside, so otherwise the version recorded in
+// the manifest has to match the release tag.
+func (in *installer) alreadyInstalled(ctx context.Context, app appInfo, target string, log logger) bool {
+	if _, err := os.Stat(target); err != nil {
+		return false
+	}
+
+	if app.LockedSHA256 != "" {
+		_, err := verifyDigest(target, app.LockedSHA256, []string{AlgoSHA256}, log)
+		return err == nil
+	}
+	if archiveExt(app.Name) == "" {
+		if expected, ok := in.opts.PinnedChecksums[app.Repo+"@"+app.Version]; ok {
+			_, err := verifyDigest(target, expected, detectAlgos(AlgoAuto, "", expected), log)
+			return err == nil
+		}
+		if app.ChecksumURL != "" {
+			_, err := verifyChecksumAsset(ctx, in.opts.API, app, target, in.opts.ChecksumAlgo, log)
+			return err == nil
+		}
+	}
+
+	if app.Version == "" {
+		return false
+	}
+	installed, err := loadManifest(in.dir)
+	if err != nil {
+		return false
+	}
+	current, ok := installed[target]
+	return ok && current.Repo == app.Repo && current.Version == app.Version
+}
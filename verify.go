@@ -0,0 +1,224 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"golang.org/x/crypto/blake2b"
+)
+
+// Verifier checks detached signatures made with one kind of key, so
+// minisign and GPG can sit side by side behind --verify-key.
+type Verifier interface {
+	// Extensions are the suffixes the signature of an asset is published
+	// under, tried in order.
+	Extensions() []string
+	// Verify checks the file at path against signature.
+	Verify(path string, signature []byte) error
+}
+
+// loadVerifier reads the public key given to --verify-key, a minisign key
+// or a GPG key, armored or not.
+func loadVerifier(path string) (Verifier, error) {
+	path, err := expandPath(path)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if key, err := parseMinisignKey(data); err == nil {
+		return key, nil
+	}
+	keyring, err := openpgp.ReadArmoredKeyRing(bytes.NewReader(data))
+	if err != nil {
+		keyring, err = openpgp.ReadKeyRing(bytes.NewReader(data))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s is neither a minisign nor a GPG public key", path)
+	}
+	return gpgVerifier{keyring: keyring}, nil
+}
+
+var (
+	verifiersMu sync.Mutex
+	verifiers   = make(map[string]Verifier)
+)
+
+// verifierFor returns the key an app's signature is checked with: the one
+// its repo list entry names, or --verify-key. Keys named in the repo list
+// are read once however many repos share them.
+func verifierFor(opts options, keyPath string) (Verifier, error) {
+	if keyPath == "" {
+		return opts.Verifier, nil
+	}
+	verifiersMu.Lock()
+	defer verifiersMu.Unlock()
+	if verifier, ok := verifiers[keyPath]; ok {
+		return verifier, nil
+	}
+	verifier, err := loadVerifier(keyPath)
+	if err != nil {
+		return nil, err
+	}
+	verifiers[keyPath] = verifier
+	return verifier, nil
+}
+
+// signatureAsset finds the signature of assetName among a release's assets.
+func signatureAsset(verifier Verifier, assetName string, assets []releaseAsset) (releaseAsset, bool) {
+	for _, ext := range verifier.Extensions() {
+		for _, asset := range assets {
+			if asset.Name == assetName+ext {
+				return asset, true
+			}
+		}
+	}
+	return releaseAsset{}, false
+}
+
+// verifySignature downloads the app's signature and checks the downloaded
+// file against it.
+func verifySignature(ctx context.Context, policy retryPolicy, verifier Verifier, app appInfo, path string) error {
+	resp, err := policy.get(ctx, app.SignatureURL)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		return fmt.Errorf("signature file returned response code %d", resp.StatusCode)
+	}
+
+	signature, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	return verifier.Verify(path, signature)
+}
+
+// minisignKey is a minisign public key: "Ed", an 8 byte key id and the
+// Ed25519 key, base64 encoded below an untrusted comment.
+type minisignKey struct {
+	id  []byte
+	key ed25519.PublicKey
+}
+
+func parseMinisignKey(data []byte) (*minisignKey, error) {
+	var encoded string
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.HasPrefix(line, "untrusted comment:") {
+			encoded = line
+			break
+		}
+	}
+
+	raw, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, err
+	}
+	if len(raw) != 2+8+ed25519.PublicKeySize || string(raw[:2]) != "Ed" {
+		return nil, fmt.Errorf("not a minisign public key")
+	}
+	return &minisignKey{id: raw[2:10], key: ed25519.PublicKey(raw[10:])}, nil
+}
+
+func (k *minisignKey) Extensions() []string {
+	return []string{".minisig"}
+}
+
+// Verify checks a .minisig file: an untrusted comment, the signature, a
+// trusted comment and a signature over the signature and trusted comment.
+// "ED" signatures sign the file's BLAKE2b-512 hash, legacy "Ed" ones the
+// file itself.
+func (k *minisignKey) Verify(path string, signature []byte) error {
+	var lines []string
+	for _, line := range strings.Split(string(signature), "\n") {
+		if line = strings.TrimRight(line, "\r"); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	if len(lines) < 4 {
+		return fmt.Errorf("malformed minisign signature")
+	}
+
+	sig, err := base64.StdEncoding.DecodeString(lines[1])
+	if err != nil || len(sig) != 2+8+ed25519.SignatureSize {
+		return fmt.Errorf("malformed minisign signature")
+	}
+	alg, id, sig := string(sig[:2]), sig[2:10], sig[10:]
+	if !bytes.Equal(id, k.id) {
+		return fmt.Errorf("signed with a different key")
+	}
+
+	var message []byte
+	switch alg {
+	case "ED":
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		h, _ := blake2b.New512(nil)
+		if _, err := io.Copy(h, file); err != nil {
+			return err
+		}
+		message = h.Sum(nil)
+	case "Ed":
+		message, err = os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unsupported minisign signature algorithm %q", alg)
+	}
+	if !ed25519.Verify(k.key, message, sig) {
+		return fmt.Errorf("minisign signature doesn't match")
+	}
+
+	trusted, ok := strings.CutPrefix(lines[2], "trusted comment: ")
+	if !ok {
+		return fmt.Errorf("malformed minisign signature")
+	}
+	global, err := base64.StdEncoding.DecodeString(lines[3])
+	if err != nil || !ed25519.Verify(k.key, append(append([]byte{}, sig...), trusted...), global) {
+		return fmt.Errorf("minisign trusted comment signature doesn't match")
+	}
+	return nil
+}
+
+type gpgVerifier struct {
+	keyring openpgp.EntityList
+}
+
+func (g gpgVerifier) Extensions() []string {
+	return []string{".sig", ".asc"}
+}
+
+func (g gpgVerifier) Verify(path string, signature []byte) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if bytes.HasPrefix(bytes.TrimSpace(signature), []byte("-----BEGIN PGP SIGNATURE-----")) {
+		_, err = openpgp.CheckArmoredDetachedSignature(g.keyring, file, bytes.NewReader(signature), nil)
+	} else {
+		_, err = openpgp.CheckDetachedSignature(g.keyring, file, bytes.NewReader(signature), nil)
+	}
+	if err != nil {
+		return fmt.Errorf("GPG signature doesn't match: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+)
+
+func TestGPGVerifier(t *testing.T) {
+	entity, err := openpgp.NewEntity("maintainer", "", "maintainer@example.com", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+
+	var key bytes.Buffer
+	w, err := armor.Encode(&key, openpgp.PublicKeyType, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := entity.Serialize(w); err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+	keyPath := filepath.Join(dir, "key.asc")
+	if err := os.WriteFile(keyPath, key.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	binary := filepath.Join(dir, "tool")
+	if err := os.WriteFile(binary, []byte("release build"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	var armored, raw bytes.Buffer
+	if err := openpgp.ArmoredDetachSign(&armored, entity, bytes.NewReader([]byte("release build")), nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := openpgp.DetachSign(&raw, entity, bytes.NewReader([]byte("release build")), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	verifier, err := loadVerifier(keyPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := verifier.Verify(binary, armored.Bytes()); err != nil {
+		t.Errorf("armored signature: %v", err)
+	}
+	if err := verifier.Verify(binary, raw.Bytes()); err != nil {
+		t.Errorf("binary signature: %v", err)
+	}
+
+	if err := os.WriteFile(binary, []byte("tampered build"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := verifier.Verify(binary, armored.Bytes()); err == nil {
+		t.Error("a tampered file passed")
+	}
+}
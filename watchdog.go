@@ -0,0 +1,176 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type stallReport struct {
+	App       string
+	Worker    int
+	Stalls    int
+	Recovered bool
+}
+
+// watchdog cancels downloads that stop making progress for longer than idle
+// and retries them, keeping track of which apps and workers were affected.
+type watchdog struct {
+	idle    time.Duration
+	retries int
+	policy  retryPolicy
+	events  *eventStream
+
+	// progress shows the downloads of installAll on board.
+	progress bool
+	board    *progressBoard
+
+	mu      sync.Mutex
+	reports []stallReport
+}
+
+type idleReader struct {
+	r    io.Reader
+	last atomic.Int64
+
+	read       int64
+	lastReport time.Time
+	onProgress func(read int64)
+}
+
+func (ir *idleReader) Read(p []byte) (int, error) {
+	n, err := ir.r.Read(p)
+	if n > 0 {
+		ir.last.Store(time.Now().UnixNano())
+		ir.read += int64(n)
+		if ir.onProgress != nil && (time.Since(ir.lastReport) >= 500*time.Millisecond || err == io.EOF) {
+			ir.lastReport = time.Now()
+			ir.onProgress(ir.read)
+		}
+	}
+	return n, err
+}
+
+func (wd *watchdog) download(ctx context.Context, app appInfo, worker int, target string, log logger) error {
+	stalls := 0
+	for {
+		stalled, err := wd.attempt(ctx, app, target)
+		if !stalled {
+			if stalls > 0 {
+				wd.report(stallReport{App: app.Name, Worker: worker, Stalls: stalls, Recovered: err == nil})
+			}
+			return err
+		}
+
+		stalls++
+		log.infof("Download of %s on worker %d made no progress for %s, cancelled it", app.Name, worker, wd.idle)
+		if stalls > wd.retries {
+			os.Remove(target)
+			wd.report(stallReport{App: app.Name, Worker: worker, Stalls: stalls})
+			return fmt.Errorf("download stalled %d times", stalls)
+		}
+		log.infof("Retrying %s (%d of %d)", app.Name, stalls, wd.retries)
+	}
+}
+
+func (wd *watchdog) report(r stallReport) {
+	wd.mu.Lock()
+	defer wd.mu.Unlock()
+	wd.reports = append(wd.reports, r)
+}
+
+func (wd *watchdog) attempt(parent context.Context, app appInfo, target string) (bool, error) {
+	ctx, cancel := context.WithCancel(parent)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, app.DownloadURL, nil)
+	if app.AssetURL != "" && githubToken() != "" {
+		// Assets of private repos can only be downloaded through the API.
+		req, err = http.NewRequestWithContext(ctx, http.MethodGet, app.AssetURL, nil)
+		if err == nil {
+			req.Header.Set("Accept", "application/octet-stream")
+		}
+	}
+	if err != nil {
+		return false, err
+	}
+	resp, err := wd.policy.do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		return false, fmt.Errorf("received non-200 response code: %d", resp.StatusCode)
+	}
+
+	out, err := os.Create(target)
+	if err != nil {
+		return false, err
+	}
+	defer out.Close()
+
+	body := &idleReader{r: resp.Body}
+	if wd.events != nil {
+		body.onProgress = func(read int64) {
+			wd.events.emit(event{Type: EventDownloadProgress, Repo: app.Repo, App: app.Name, Bytes: read, Total: resp.ContentLength})
+		}
+	}
+	body.last.Store(time.Now().UnixNano())
+
+	var stalled atomic.Bool
+	done := make(chan struct{})
+	defer close(done)
+	if wd.idle > 0 {
+		go func() {
+			interval := wd.idle / 4
+			if interval < 100*time.Millisecond {
+				interval = 100 * time.Millisecond
+			}
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-done:
+					return
+				case <-ticker.C:
+					if time.Since(time.Unix(0, body.last.Load())) > wd.idle {
+						stalled.Store(true)
+						cancel()
+						return
+					}
+				}
+			}
+		}()
+	}
+
+	var dst io.Writer = out
+	if wd.board != nil {
+		pw := wd.board.start(app.Name, resp.ContentLength)
+		defer wd.board.stop(pw)
+		dst = io.MultiWriter(out, pw)
+	}
+	_, err = io.Copy(dst, body)
+	if stalled.Load() {
+		return true, err
+	}
+	return false, err
+}
+
+func (wd *watchdog) printReport() {
+	if len(wd.reports) == 0 {
+		return
+	}
+	console.infof("\nStalled downloads:")
+	for _, report := range wd.reports {
+		status := red("failed")
+		if report.Recovered {
+			status = green("recovered after retry")
+		}
+		console.infof("  %s on worker %d: stalled %d time(s), %s", report.App, report.Worker, report.Stalls, status)
+	}
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWatchdogReportsTheWorker(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	policy := testAPI(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("partial"))
+		w.(http.Flusher).Flush()
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	wd := &watchdog{idle: 50 * time.Millisecond, retries: 1, policy: policy}
+	app := appInfo{Repo: "o/tool", Name: "tool-linux-amd64", DownloadURL: apiBase + "/tool-linux-amd64"}
+
+	var out bytes.Buffer
+	err := wd.download(context.Background(), app, 3, filepath.Join(t.TempDir(), "tool"), logger{out: &out})
+	if err == nil {
+		t.Fatal("a download that never finished succeeded")
+	}
+	if !strings.Contains(out.String(), "Download of tool-linux-amd64 on worker 3 made no progress") {
+		t.Errorf("the stall message doesn't name the worker: %q", out.String())
+	}
+	if len(wd.reports) != 1 || wd.reports[0].Worker != 3 || wd.reports[0].Stalls != 2 {
+		t.Errorf("got reports %+v, want one for worker 3 with 2 stalls", wd.reports)
+	}
+	report := captureStdout(t, wd.printReport)
+	if !strings.Contains(report, "tool-linux-amd64 on worker 3: stalled 2 time(s)") {
+		t.Errorf("the report doesn't name the worker: %q", report)
+	}
+}